@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+// ensureCollection memastikan collection ada dan memiliki semua field yang dibutuhkan.
+// configure (opsional) dipanggil sebelum save untuk mengatur rules/index.
+func ensureCollection(dao *daos.Dao, name string, fields []schema.SchemaField, configure func(col *models.Collection)) (*models.Collection, error) {
+	col, err := dao.FindCollectionByNameOrId(name)
+	if err != nil {
+		log.Printf("⚠️ Collection '%s' not found, creating...", name)
+		col = &models.Collection{}
+		col.Name = name
+		col.Type = models.CollectionTypeBase
+	}
+
+	// Sync Schema Fields
+	for i := range fields {
+		if col.Schema.GetFieldByName(fields[i].Name) == nil {
+			col.Schema.AddField(&fields[i])
+		}
+	}
+
+	if configure != nil {
+		configure(col)
+	}
+
+	if err := dao.SaveCollection(col); err != nil {
+		log.Printf("❌ Failed to save collection '%s': %v", name, err)
+		return nil, err
+	}
+
+	return col, nil
+}
+
+// projectRelation builds a single-select relation field pointing to the projects collection
+func projectRelation(name string, projectsCol *models.Collection, required bool) schema.SchemaField {
+	maxSelect := 1
+	return schema.SchemaField{
+		Name:     name,
+		Type:     schema.FieldTypeRelation,
+		Required: required,
+		Options: &schema.RelationOptions{
+			CollectionId:  projectsCol.Id,
+			CascadeDelete: true,
+			MaxSelect:     &maxSelect,
+		},
+	}
+}
+
+// ownerRelation builds an optional single-select relation field pointing to the users collection
+func ownerRelation(name string, usersCol *models.Collection) schema.SchemaField {
+	maxSelect := 1
+	return schema.SchemaField{
+		Name: name,
+		Type: schema.FieldTypeRelation,
+		Options: &schema.RelationOptions{
+			CollectionId: usersCol.Id,
+			MaxSelect:    &maxSelect,
+		},
+	}
+}
+
+// relationField builds a single-select relation field to any collection
+func relationField(name string, target *models.Collection, required bool, cascade bool) schema.SchemaField {
+	maxSelect := 1
+	return schema.SchemaField{
+		Name:     name,
+		Type:     schema.FieldTypeRelation,
+		Required: required,
+		Options: &schema.RelationOptions{
+			CollectionId:  target.Id,
+			CascadeDelete: cascade,
+			MaxSelect:     &maxSelect,
+		},
+	}
+}
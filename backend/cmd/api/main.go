@@ -5,20 +5,38 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+	"github.com/senvanda/backend/internal/autoscale"
 	"github.com/senvanda/backend/internal/cicd"
 	"github.com/senvanda/backend/internal/container"
+	"github.com/senvanda/backend/internal/cronjob"
 	"github.com/senvanda/backend/internal/deployment"
+	"github.com/senvanda/backend/internal/events"
 	"github.com/senvanda/backend/internal/git"
+	"github.com/senvanda/backend/internal/history"
 	"github.com/senvanda/backend/internal/infrastructure/caddy"
 	"github.com/senvanda/backend/internal/infrastructure/docker"
 	"github.com/senvanda/backend/internal/infrastructure/woodpecker"
+	"github.com/senvanda/backend/internal/logs"
+	"github.com/senvanda/backend/internal/metrics"
 	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/preview"
+	"github.com/senvanda/backend/internal/queue"
+	"github.com/senvanda/backend/internal/quota"
+	"github.com/senvanda/backend/internal/secrets"
+	"github.com/senvanda/backend/internal/team"
+	"github.com/senvanda/backend/internal/terminal"
+	"github.com/senvanda/backend/internal/token"
 	"github.com/senvanda/backend/internal/webhook"
 )
 
@@ -26,18 +44,65 @@ func main() {
 	app := pocketbase.New()
 
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		// 0. Ensure 'projects' Collection Exists & Has Correct Schema
-		col, err := app.Dao().FindCollectionByNameOrId("projects")
+		users, err := app.Dao().FindCollectionByNameOrId("users")
 		if err != nil {
-			log.Println("⚠️ Collection 'projects' not found, creating...")
-			col = &models.Collection{}
-			col.Name = "projects"
-			col.Type = models.CollectionTypeBase
+			return err
 		}
 
-		// Sync Schema Fields
-		requiredFields := []schema.SchemaField{
+		// Teams come first: projects can belong to one. Membership and invitations are
+		// changed through /api/senvanda/teams; members can only read them directly.
+		teams, err := ensureCollection(app.Dao(), "teams", []schema.SchemaField{
 			{Name: "name", Type: schema.FieldTypeText, Required: true},
+			ownerRelation("created_by", users),
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := ensureCollection(app.Dao(), "team_members", []schema.SchemaField{
+			relationField("team", teams, true, true),
+			relationField("user", users, true, true),
+			{Name: "role", Type: schema.FieldTypeText, Required: true}, // owner, developer, viewer
+		}, func(col *models.Collection) {
+			member := "@request.auth.id != '' && @collection.team_members.team ?= team && @collection.team_members.user ?= @request.auth.id"
+			col.ListRule = &member
+			col.ViewRule = &member
+			col.Indexes = types.JsonArray[string]{
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_team_members_team_user ON team_members (team, user)",
+			}
+		}); err != nil {
+			return err
+		}
+		if _, err := ensureCollection(app.Dao(), "team_invitations", []schema.SchemaField{
+			relationField("team", teams, true, true),
+			{Name: "email", Type: schema.FieldTypeEmail, Required: true},
+			{Name: "role", Type: schema.FieldTypeText, Required: true},
+			{Name: "token", Type: schema.FieldTypeText, Required: true},
+			{Name: "status", Type: schema.FieldTypeText}, // pending, accepted, revoked
+			{Name: "expires_at", Type: schema.FieldTypeDate},
+			ownerRelation("invited_by", users),
+			ownerRelation("accepted_by", users),
+			{Name: "accepted_at", Type: schema.FieldTypeDate},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_team_invitations_token ON team_invitations (token)",
+			}
+		}); err != nil {
+			return err
+		}
+
+		// Members see their teams (set once team_members exists)
+		teamMember := "@request.auth.id != '' && @collection.team_members.team ?= id && @collection.team_members.user ?= @request.auth.id"
+		teams.ListRule = &teamMember
+		teams.ViewRule = &teamMember
+		if err := app.Dao().SaveCollection(teams); err != nil {
+			return err
+		}
+
+		// 0. Ensure 'projects' Collection Exists & Has Correct Schema
+		col, err := ensureCollection(app.Dao(), "projects", []schema.SchemaField{
+			{Name: "name", Type: schema.FieldTypeText, Required: true},
+			ownerRelation("user", users),               // Empty = admin-managed (discovered, adopted)
+			relationField("team", teams, false, false), // Shared with the team's members by role
 			{Name: "status", Type: schema.FieldTypeText},
 			{Name: "webhook_token", Type: schema.FieldTypeText},
 			{Name: "repo_owner", Type: schema.FieldTypeText},
@@ -49,28 +114,242 @@ func main() {
 			{Name: "volumes", Type: schema.FieldTypeJson},
 			{Name: "settings", Type: schema.FieldTypeJson},
 			{Name: "current_action", Type: schema.FieldTypeText}, // For Real-time UX
-			{Name: "category", Type: schema.FieldTypeText},       // application, infrastructure, discovered
+			{Name: "category", Type: schema.FieldTypeText},       // application, infrastructure, discovered, preview
+			{Name: "preview_of", Type: schema.FieldTypeText},     // Parent project ID (branch previews only)
+			{Name: "preview_branch", Type: schema.FieldTypeText},
+			{Name: "last_activity", Type: schema.FieldTypeDate}, // Last push, drives the preview TTL
+		}, func(col *models.Collection) {
+			// Older databases stored the owner as plain text; same column, now a relation
+			if field := col.Schema.GetFieldByName("user"); field.Type != schema.FieldTypeRelation {
+				relation := ownerRelation("user", users)
+				field.Type = relation.Type
+				field.Options = relation.Options
+			}
+
+			// Users see their own projects and those of their teams. Owners and team
+			// developers may change them, only owners delete. Neither the owner nor the
+			// team can be changed here (the team moves via /deploy/:id/team). Admins bypass rules.
+			owner := "(@request.auth.id != '' && user = @request.auth.id)"
+			member := "(@request.auth.id != '' && team != '' && @collection.team_members.team ?= team && @collection.team_members.user ?= @request.auth.id)"
+			developer := "(@request.auth.id != '' && team != '' && @collection.team_members.team ?= team && @collection.team_members.user ?= @request.auth.id && (@collection.team_members.role ?= 'owner' || @collection.team_members.role ?= 'developer'))"
+			teamOwner := "(@request.auth.id != '' && team != '' && @collection.team_members.team ?= team && @collection.team_members.user ?= @request.auth.id && @collection.team_members.role ?= 'owner')"
+			keep := "@request.data.user:isset = false && @request.data.team:isset = false"
+
+			view := owner + " || " + member
+			create := "@request.auth.id != '' && @request.data.user = @request.auth.id && @request.data.team:isset = false"
+			update := "(" + owner + " || " + developer + ") && " + keep
+			remove := owner + " || " + teamOwner
+			col.ListRule = &view
+			col.ViewRule = &view
+			col.CreateRule = &create
+			col.UpdateRule = &update
+			col.DeleteRule = &remove
+		})
+		if err != nil {
+			return err
+		}
+
+		// 0b. Deployment History (one record per deploy attempt)
+		if _, err := ensureCollection(app.Dao(), "deployments", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "trigger", Type: schema.FieldTypeText}, // webhook, manual, ci, rollback
+			{Name: "image", Type: schema.FieldTypeText},
+			{Name: "image_digest", Type: schema.FieldTypeText},
+			{Name: "commit_sha", Type: schema.FieldTypeText},
+			{Name: "build_number", Type: schema.FieldTypeNumber},
+			{Name: "started_at", Type: schema.FieldTypeDate},
+			{Name: "finished_at", Type: schema.FieldTypeDate},
+			{Name: "status", Type: schema.FieldTypeText}, // running, success, failed
+			{Name: "error", Type: schema.FieldTypeText},
+			{Name: "snapshot", Type: schema.FieldTypeJson},    // settings, volumes & port at deploy time
+			{Name: "rollback_of", Type: schema.FieldTypeText}, // restored deployment ID
+			{Name: "job", Type: schema.FieldTypeText},         // deploy_jobs ID
+			{Name: "release_output", Type: schema.FieldTypeText},
+			{Name: "release_exit_code", Type: schema.FieldTypeNumber},
+		}, nil); err != nil {
+			return err
+		}
+
+		// 0c. Deploy Queue (serializes deploys per project, survives restarts)
+		if _, err := ensureCollection(app.Dao(), "deploy_jobs", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "kind", Type: schema.FieldTypeText}, // deploy, redeploy, rollback
+			{Name: "trigger", Type: schema.FieldTypeText},
+			{Name: "params", Type: schema.FieldTypeJson},
+			{Name: "status", Type: schema.FieldTypeText}, // queued, running, done, failed, superseded
+			{Name: "error", Type: schema.FieldTypeText},
+			{Name: "superseded_by", Type: schema.FieldTypeText},
+			{Name: "started_at", Type: schema.FieldTypeDate},
+			{Name: "finished_at", Type: schema.FieldTypeDate},
+		}, nil); err != nil {
+			return err
+		}
+
+		// 0d. Deploy Events (typed progress, replayed to late SSE subscribers)
+		if _, err := ensureCollection(app.Dao(), "deploy_events", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "deployment", Type: schema.FieldTypeText},
+			{Name: "seq", Type: schema.FieldTypeNumber},
+			{Name: "type", Type: schema.FieldTypeText},
+			{Name: "phase", Type: schema.FieldTypeText},
+			{Name: "message", Type: schema.FieldTypeText},
+			{Name: "data", Type: schema.FieldTypeJson},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE INDEX IF NOT EXISTS idx_deploy_events_deployment ON deploy_events (deployment, seq)",
+			}
+		}); err != nil {
+			return err
+		}
+
+		// 0e. Autoscale Decisions (every scale up/down the autoscaler made or held back)
+		if _, err := ensureCollection(app.Dao(), "autoscale_decisions", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "decision", Type: schema.FieldTypeText}, // scale_up, scale_down, cooldown
+			{Name: "replicas", Type: schema.FieldTypeNumber},
+			{Name: "target_replicas", Type: schema.FieldTypeNumber},
+			{Name: "cpu_percent", Type: schema.FieldTypeNumber},
+			{Name: "memory_percent", Type: schema.FieldTypeNumber},
+			{Name: "reason", Type: schema.FieldTypeText},
+			{Name: "job", Type: schema.FieldTypeText}, // deploy_jobs ID of the scale job
+		}, nil); err != nil {
+			return err
+		}
+
+		// 0f. Cron Runs (one record per scheduled or manual run of a project's cronJobs)
+		if _, err := ensureCollection(app.Dao(), "cron_runs", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "job", Type: schema.FieldTypeText},
+			{Name: "schedule", Type: schema.FieldTypeText},
+			{Name: "command", Type: schema.FieldTypeText},
+			{Name: "trigger", Type: schema.FieldTypeText}, // schedule, manual
+			{Name: "image", Type: schema.FieldTypeText},
+			{Name: "status", Type: schema.FieldTypeText}, // running, succeeded, failed, timed_out
+			{Name: "exit_code", Type: schema.FieldTypeNumber},
+			{Name: "duration_ms", Type: schema.FieldTypeNumber},
+			{Name: "output", Type: schema.FieldTypeText},
+			{Name: "error", Type: schema.FieldTypeText},
+			{Name: "started_at", Type: schema.FieldTypeDate},
+			{Name: "finished_at", Type: schema.FieldTypeDate},
+		}, nil); err != nil {
+			return err
+		}
+
+		// 0g. Terminal Sessions (audit log of every web terminal opened into a container)
+		if _, err := ensureCollection(app.Dao(), "exec_sessions", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "user", Type: schema.FieldTypeText},
+			{Name: "container", Type: schema.FieldTypeText},
+			{Name: "shell", Type: schema.FieldTypeText},
+			{Name: "remote_addr", Type: schema.FieldTypeText},
+			{Name: "started_at", Type: schema.FieldTypeDate},
+			{Name: "ended_at", Type: schema.FieldTypeDate},
+			{Name: "end_reason", Type: schema.FieldTypeText}, // closed, exited, idle_timeout, error
+			{Name: "exit_code", Type: schema.FieldTypeNumber},
+			{Name: "duration_ms", Type: schema.FieldTypeNumber},
+			{Name: "error", Type: schema.FieldTypeText},
+		}, nil); err != nil {
+			return err
+		}
+
+		// 0h. Collected Logs (container output kept after the container is gone, see logs.Collector)
+		if _, err := ensureCollection(app.Dao(), "log_lines", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "deployment", Type: schema.FieldTypeText}, // Deployment that started the container
+			{Name: "container", Type: schema.FieldTypeText},
+			{Name: "container_id", Type: schema.FieldTypeText},
+			{Name: "stream", Type: schema.FieldTypeText}, // stdout, stderr
+			{Name: "time", Type: schema.FieldTypeDate},
+			{Name: "text", Type: schema.FieldTypeText},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE INDEX IF NOT EXISTS idx_log_lines_project_time ON log_lines (project, time)",
+				"CREATE INDEX IF NOT EXISTS idx_log_lines_container_time ON log_lines (container_id, time)",
+			}
+		}); err != nil {
+			return err
 		}
 
-		for _, f := range requiredFields {
-			if col.Schema.GetFieldByName(f.Name) == nil {
-				col.Schema.AddField(&f)
+		// 0i. Resource Metrics (per-project sums per minute and per hour, see metrics.Service)
+		if _, err := ensureCollection(app.Dao(), "metric_points", []schema.SchemaField{
+			projectRelation("project", col, true),
+			{Name: "resolution", Type: schema.FieldTypeText}, // 1m, 1h
+			{Name: "bucket", Type: schema.FieldTypeDate},     // Start of the minute/hour
+			{Name: "samples", Type: schema.FieldTypeNumber},
+			{Name: "cpu", Type: schema.FieldTypeNumber}, // Sums over samples, divide by samples
+			{Name: "memory", Type: schema.FieldTypeNumber},
+			{Name: "memory_limit", Type: schema.FieldTypeNumber},
+			{Name: "net_rx", Type: schema.FieldTypeNumber}, // Bytes per second
+			{Name: "net_tx", Type: schema.FieldTypeNumber},
+			{Name: "block_read", Type: schema.FieldTypeNumber},
+			{Name: "block_write", Type: schema.FieldTypeNumber},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_metric_points_bucket ON metric_points (project, resolution, bucket)",
+				"CREATE INDEX IF NOT EXISTS idx_metric_points_resolution_bucket ON metric_points (resolution, bucket)",
 			}
+		}); err != nil {
+			return err
 		}
 
-		// PERMISSIVE RULES FOR TESTING
-		rule := ""
-		col.ListRule = &rule
-		col.ViewRule = &rule
-		col.CreateRule = &rule
-		col.UpdateRule = &rule
+		// 0j. Quotas (per user limits; a record without user is the default for everyone, managed by admins)
+		if _, err := ensureCollection(app.Dao(), "quotas", []schema.SchemaField{
+			{Name: "user", Type: schema.FieldTypeText}, // Empty = default quota
+			{Name: "max_projects", Type: schema.FieldTypeNumber},
+			{Name: "max_cpu", Type: schema.FieldTypeNumber}, // Cores, 0 = unlimited
+			{Name: "max_memory_mb", Type: schema.FieldTypeNumber},
+			{Name: "max_volumes", Type: schema.FieldTypeNumber},
+			{Name: "max_replicas", Type: schema.FieldTypeNumber}, // Per project
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_quotas_user ON quotas (user)",
+			}
+		}); err != nil {
+			return err
+		}
 
-		if err := app.Dao().SaveCollection(col); err != nil {
-			log.Printf("❌ Failed to save collection: %v", err)
+		// 0k. Audit Log (append-only; project is plain text so events outlive deleted projects)
+		if _, err := ensureCollection(app.Dao(), "audit_events", []schema.SchemaField{
+			{Name: "actor_type", Type: schema.FieldTypeText}, // user, admin, webhook, ci, anonymous
+			{Name: "actor_id", Type: schema.FieldTypeText},
+			{Name: "actor", Type: schema.FieldTypeText}, // Email or source, for reading
+			{Name: "action", Type: schema.FieldTypeText},
+			{Name: "project", Type: schema.FieldTypeText},
+			{Name: "method", Type: schema.FieldTypeText},
+			{Name: "route", Type: schema.FieldTypeText},
+			{Name: "ip", Type: schema.FieldTypeText},
+			{Name: "params", Type: schema.FieldTypeJson},
+			{Name: "status", Type: schema.FieldTypeNumber},
+			{Name: "outcome", Type: schema.FieldTypeText}, // success, failure, queued
+			{Name: "error", Type: schema.FieldTypeText},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events (created)",
+				"CREATE INDEX IF NOT EXISTS idx_audit_events_project ON audit_events (project, created)",
+				"CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, created)",
+			}
+		}); err != nil {
 			return err
 		}
 
-		if err := app.Dao().SaveCollection(col); err != nil {
+		// 0l. Personal Access Tokens (only the SHA-256 is stored; managed through /api/senvanda/tokens)
+		if _, err := ensureCollection(app.Dao(), "api_tokens", []schema.SchemaField{
+			relationField("user", users, false, true), // Empty for tokens of admins
+			{Name: "admin", Type: schema.FieldTypeText},
+			{Name: "name", Type: schema.FieldTypeText, Required: true},
+			{Name: "hash", Type: schema.FieldTypeText, Required: true},
+			{Name: "hint", Type: schema.FieldTypeText},
+			{Name: "scopes", Type: schema.FieldTypeJson},   // projects:read, projects:deploy, logs:read, admin
+			{Name: "projects", Type: schema.FieldTypeJson}, // Empty = all projects of the owner
+			{Name: "expires_at", Type: schema.FieldTypeDate},
+			{Name: "last_used_at", Type: schema.FieldTypeDate},
+			{Name: "last_used_ip", Type: schema.FieldTypeText},
+			{Name: "revoked_at", Type: schema.FieldTypeDate},
+		}, func(col *models.Collection) {
+			col.Indexes = types.JsonArray[string]{
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_hash ON api_tokens (hash)",
+			}
+		}); err != nil {
 			return err
 		}
 
@@ -112,31 +391,172 @@ func main() {
 		woodpeckerClient := woodpecker.NewClient(ciURL, ciToken)
 
 		// 2. Inisialisasi Logic Layer (The Brain)
-		orchestratorSvc := orchestrator.NewService(app, dockerClient, caddyClient, woodpeckerClient)
-		deployHandler := orchestrator.NewDeploymentHandler(orchestratorSvc)
+		historySvc := history.NewService(app)
+		historyHandler := history.NewHandler(historySvc)
+
+		// Deploy Queue: one active job per project, global concurrency limit
+		concurrency, _ := strconv.Atoi(os.Getenv("SENVANDA_DEPLOY_CONCURRENCY"))
+		if concurrency <= 0 {
+			concurrency = 2
+		}
+		queueSvc := queue.NewService(app, concurrency)
+		queueHandler := queue.NewHandler(queueSvc)
+
+		eventsSvc := events.NewService(app)
+		eventsHandler := events.NewHandler(eventsSvc)
+
+		orchestratorSvc := orchestrator.NewService(app, dockerClient, caddyClient, woodpeckerClient, historySvc, eventsSvc)
+
+		// Quotas: checked before every deploy and scale up
+		quotaSvc := quota.NewService(app)
+		quotaHandler := quota.NewHandler(quotaSvc)
+		orchestratorSvc.SetAdmission(quotaSvc.Admit)
+
+		// Secret env vars: encrypted with SENVANDA_SECRET_KEY, only opened for new containers
+		secretKeys, err := secrets.FromEnv()
+		if err != nil {
+			return fmt.Errorf("invalid SENVANDA_SECRET_KEY: %w", err)
+		}
+		if secretKeys == nil {
+			log.Println("⚠️ SENVANDA_SECRET_KEY is not set: env vars cannot be marked secret")
+		}
+		secretsSvc := secrets.NewService(app, secretKeys)
+		secretsSvc.BindHooks()
+		secretsHandler := secrets.NewHandler(secretsSvc)
+		orchestratorSvc.SetEnvDecrypter(secretsSvc.Keys().DecryptEnv) // nil keyring: refuses encrypted values
+		deployHandler := orchestrator.NewDeploymentHandler(orchestratorSvc, queueSvc)
+
+		// Teams: shared projects with owner/developer/viewer roles, invitations by email
+		teamSvc := team.NewService(app)
+		teamHandler := team.NewHandler(teamSvc)
+
+		previewSvc := preview.NewService(app, queueSvc)
+		previewHandler := preview.NewHandler(previewSvc)
 
 		webhookSvc := webhook.NewService(app)
-		webhookHandler := webhook.NewHandler(webhookSvc, orchestratorSvc)
+		webhookHandler := webhook.NewHandler(webhookSvc, orchestratorSvc, previewSvc)
 
 		// Legacy/Dashboard Support
 		containerSvc := container.NewService(dockerClient.GetRawClient())
 		gitSvc := git.NewService()
 		cicdSvc := cicd.NewService()
-		deploymentSvc := deployment.NewService(app, containerSvc, gitSvc, cicdSvc)
+		deploymentSvc := deployment.NewService(app, containerSvc, gitSvc, cicdSvc, queueSvc, quotaSvc)
 		deploymentHandler := deployment.NewHandler(deploymentSvc)
 
+		// Job executors must be registered before the queue starts (recovery).
+		// The orchestrator pipeline runs every deploy kind.
+		orchestratorSvc.RegisterJobs(queueSvc)
+		queueSvc.Start()
+
+		// Autoscaler: samples Docker stats, scales through the queue
+		autoscaleInterval, _ := time.ParseDuration(os.Getenv("SENVANDA_AUTOSCALE_INTERVAL"))
+		autoscaleSvc := autoscale.NewService(app, dockerClient, orchestratorSvc, queueSvc, autoscaleInterval)
+		autoscaleHandler := autoscale.NewHandler(autoscaleSvc)
+		autoscaleSvc.Start()
+
+		// Preview janitor: removes previews idle past their TTL
+		previewSvc.StartJanitor(10 * time.Minute)
+
+		// Cron jobs: one-off containers from the project's current image
+		cronSvc := cronjob.NewService(app, orchestratorSvc)
+		cronHandler := cronjob.NewHandler(cronSvc)
+		cronSvc.Start()
+
+		// Web Terminal: TTY exec sessions, closed after SENVANDA_EXEC_IDLE_TIMEOUT without activity
+		execIdleTimeout, _ := time.ParseDuration(os.Getenv("SENVANDA_EXEC_IDLE_TIMEOUT"))
+		terminalSvc := terminal.NewService(app, dockerClient, orchestratorSvc, execIdleTimeout)
+		terminalHandler := terminal.NewHandler(terminalSvc)
+
+		// Container Logs: demuxed, interleaved across replicas
+		logsSvc := logs.NewService(app, dockerClient, orchestratorSvc)
+		logsHandler := logs.NewHandler(logsSvc)
+		logsSvc.StartCollector(10 * time.Minute)
+
+		// Resource Metrics: Docker stats sampled into 1m/1h series for the Analytics view
+		metricsInterval, _ := time.ParseDuration(os.Getenv("SENVANDA_METRICS_INTERVAL"))
+		metricsRawRetention, _ := time.ParseDuration(os.Getenv("SENVANDA_METRICS_RAW_RETENTION"))
+		metricsRetention, _ := time.ParseDuration(os.Getenv("SENVANDA_METRICS_RETENTION"))
+		metricsSvc := metrics.NewService(app, dockerClient, metrics.Config{
+			Interval:        metricsInterval,
+			MinuteRetention: metricsRawRetention,
+			HourRetention:   metricsRetention,
+		})
+		metricsHandler := metrics.NewHandler(metricsSvc)
+		metricsSvc.Start()
+
+		// Audit Log: every control-plane change, recorded by a middleware on the whole API
+		auditSvc := audit.NewService(app)
+		auditSvc.BindHooks()
+		auditHandler := audit.NewHandler(auditSvc)
+
+		// Personal Access Tokens: `Authorization: Bearer svd_...` for scripts, scoped
+		tokenSvc := token.NewService(app)
+		tokenHandler := token.NewHandler(tokenSvc)
+
 		// 3. Register Routes
 		// Group API Public
-		apiGroup := e.Router.Group("/api/senvanda")
+		apiGroup := e.Router.Group("/api/senvanda", auditSvc.Middleware(), tokenSvc.Middleware())
 
-		// Register Webhook (from Gitea)
+		// Register Webhook (from Gitea, authenticated by the project token)
 		webhookHandler.RegisterRoutes(apiGroup)
 
-		// Register Deploy Final (from Woodpecker)
-		deployHandler.RegisterRoutes(apiGroup)
+		// Register Deploy Final (from Woodpecker, authenticated by the shared secret)
+		deployHandler.RegisterPublicRoutes(apiGroup)
+
+		// Register Token Redeploy (webhook token)
+		deploymentHandler.RegisterPublicRoutes(apiGroup)
+
+		// Everything else requires a signed-in user or admin; `:id` routes also require
+		// a role on the project: viewer to read, developer to change (admins bypass)
+		protectedGroup := apiGroup.Group("", auth.RequireAuth(app), auth.RequireProjectAccess(app))
+
+		// Register Rollback, Scale & Canary
+		deployHandler.RegisterRoutes(protectedGroup)
 
 		// Register Dashboard Routes
-		deploymentHandler.RegisterRoutes(apiGroup)
+		deploymentHandler.RegisterRoutes(protectedGroup)
+
+		// Register Deployment History (Timeline)
+		historyHandler.RegisterRoutes(protectedGroup)
+
+		// Register Deploy Queue (Job Polling)
+		queueHandler.RegisterRoutes(protectedGroup)
+
+		// Register Autoscale (Config & Decisions)
+		autoscaleHandler.RegisterRoutes(protectedGroup)
+
+		// Register Branch Previews
+		previewHandler.RegisterRoutes(protectedGroup)
+
+		// Register Cron Jobs (Runs & Manual Trigger)
+		cronHandler.RegisterRoutes(protectedGroup)
+
+		// Register Container Logs (SSE Follow)
+		logsHandler.RegisterRoutes(protectedGroup)
+
+		// Register Resource Metrics (Analytics)
+		metricsHandler.RegisterRoutes(protectedGroup)
+
+		// Register Quotas (Usage)
+		quotaHandler.RegisterRoutes(protectedGroup)
+
+		// Register Teams (Members & Invitations)
+		teamHandler.RegisterRoutes(protectedGroup)
+
+		// Register Audit Log (Query & Export)
+		auditHandler.RegisterRoutes(protectedGroup)
+
+		// Register API Tokens (Create, List, Revoke)
+		tokenHandler.RegisterRoutes(protectedGroup)
+
+		// Register Secrets (Key Rotation)
+		secretsHandler.RegisterRoutes(protectedGroup)
+
+		// Register Web Terminal (WebSocket)
+		terminalHandler.RegisterRoutes(protectedGroup)
+
+		// Register Deploy Event Stream (SSE)
+		eventsHandler.RegisterRoutes(protectedGroup)
 
 		// Test Endpoint (Bukti Kehidupan)
 		// Bisa diakses via: GET http://localhost:8090/api/senvanda/health-check
@@ -179,7 +599,7 @@ func main() {
 
 		// TEST: Add Dummy Domain
 		// POST /api/senvanda/test-caddy?domain=test.local&target=1.1.1.1:80
-		apiGroup.POST("/test-caddy", func(c echo.Context) error {
+		protectedGroup.POST("/test-caddy", func(c echo.Context) error {
 			domain := c.QueryParam("domain")
 			target := c.QueryParam("target")
 			if domain == "" || target == "" {
@@ -189,7 +609,7 @@ func main() {
 				return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			}
 			return c.JSON(http.StatusOK, map[string]string{"message": "Route added to Caddy!"})
-		})
+		}, auth.RequireAdmin())
 
 		log.Println("✅ Senvanda v2 Control Plane is Ready!")
 		return nil
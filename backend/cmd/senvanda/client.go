@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultURL = "http://127.0.0.1:8090"
+
+// requestTimeout bounds every call except followed streams
+const requestTimeout = 60 * time.Second
+
+// config is what `senvanda login` remembers between runs
+type config struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`           // PocketBase session or personal access token (svd_...)
+	Email string `json:"email,omitempty"` // Who logged in with a password
+	Admin bool   `json:"admin,omitempty"`
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv("SENVANDA_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "senvanda", "config.json"), nil
+}
+
+func loadConfig() (config, error) {
+	var cfg config
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config readable by the current user only (it holds a credential)
+func saveConfig(cfg config) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	raw, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, raw, 0o600)
+}
+
+// apiError is a non-2xx answer of the control plane
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// client calls the control plane HTTP API
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(baseURL string, token string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{}, // No timeout: followed log streams stay open
+	}
+}
+
+func (c *client) newRequest(ctx context.Context, method string, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do sends a JSON request and decodes the JSON answer into out (when not nil).
+// It returns the raw body too, so `-o json` can print exactly what the API said.
+func (c *client) do(ctx context.Context, method string, path string, body any, out any) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &unreachableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, decodeError(resp.StatusCode, raw)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, fmt.Errorf("unexpected answer from %s: %w", path, err)
+		}
+	}
+	return raw, nil
+}
+
+// decodeError reads both error shapes of the API: PocketBase's {"message": ...}
+// and the orchestrator's {"error": ...}
+func decodeError(status int, raw []byte) error {
+	var body struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	_ = json.Unmarshal(raw, &body)
+	message := body.Message
+	if body.Error != "" {
+		message = body.Error
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(raw))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &apiError{Status: status, Message: message}
+}
+
+// unreachableError means the request never got an answer
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return "cannot reach the Senvanda API: " + e.err.Error()
+}
+
+func (e *unreachableError) Unwrap() error {
+	return e.err
+}
+
+// sseEvent is one Server-Sent Event
+type sseEvent struct {
+	Name string
+	Data []byte
+}
+
+// stream opens a Server-Sent Events endpoint and calls fn for every event until the
+// server closes the stream, fn returns an error or ctx is done
+func (c *client) stream(ctx context.Context, path string, fn func(sseEvent) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return &unreachableError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		return decodeError(resp.StatusCode, raw)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var event sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event.Name != "" || event.Data != nil {
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+			event = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			// Heartbeat comment
+		case strings.HasPrefix(line, "event:"):
+			event.Name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			event.Data = append(event.Data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// query builds a query string, leaving out empty values
+func query(params map[string]string) string {
+	values := url.Values{}
+	for key, value := range params {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// envVar is one entry of GET /deploy/:id/env
+type envVar struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+func (a *app) envCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Manage project environment variables (applied on the next deploy)",
+	}
+
+	list := &cobra.Command{
+		Use:     "list <project>",
+		Aliases: []string{"ls"},
+		Short:   "List environment variables",
+		Args:    args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			projectID, err := a.resolveProject(cmd.Context(), argv[0])
+			if err != nil {
+				return err
+			}
+			var envs []envVar
+			raw, err := a.api.do(cmd.Context(), http.MethodGet, "/api/senvanda/deploy/"+projectID+"/env", nil, &envs)
+			if err != nil {
+				return err
+			}
+			return a.printEnv(raw, envs)
+		},
+	}
+
+	var (
+		secret   bool
+		redeploy bool
+		wait     bool
+		timeout  time.Duration
+	)
+	set := &cobra.Command{
+		Use:   "set <project> KEY=VALUE...",
+		Short: "Set environment variables",
+		Long: `Set environment variables. With --secret they are encrypted at rest and masked
+in every API response; a variable that already is a secret stays one.`,
+		Example: `  senvanda env set shop LOG_LEVEL=debug FEATURE_X=1 --redeploy
+  senvanda env set shop DATABASE_URL="$DATABASE_URL" --secret`,
+		Args: args(cobra.MinimumNArgs(2)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			values := make(map[string]string, len(argv)-1)
+			for _, pair := range argv[1:] {
+				key, value, ok := strings.Cut(pair, "=")
+				if !ok || key == "" {
+					return &usageError{err: fmt.Errorf("%q is not KEY=VALUE", pair)}
+				}
+				values[key] = value
+			}
+			return a.updateEnv(cmd.Context(), argv[0], map[string]any{"set": values, "secret": secret}, redeploy, wait, timeout)
+		},
+	}
+	unset := &cobra.Command{
+		Use:     "unset <project> KEY...",
+		Short:   "Remove environment variables",
+		Example: `  senvanda env unset shop FEATURE_X`,
+		Args:    args(cobra.MinimumNArgs(2)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			return a.updateEnv(cmd.Context(), argv[0], map[string]any{"unset": argv[1:]}, redeploy, wait, timeout)
+		},
+	}
+	set.Flags().BoolVar(&secret, "secret", false, "store the values as secrets")
+	for _, c := range []*cobra.Command{set, unset} {
+		c.Flags().BoolVar(&redeploy, "redeploy", false, "redeploy the project to apply the change")
+		addWaitFlags(c, &wait, &timeout)
+	}
+
+	cmd.AddCommand(list, set, unset)
+	return cmd
+}
+
+func (a *app) updateEnv(ctx context.Context, ref string, body map[string]any, redeploy bool, wait bool, timeout time.Duration) error {
+	projectID, err := a.resolveProject(ctx, ref)
+	if err != nil {
+		return err
+	}
+	var envs []envVar
+	raw, err := a.api.do(ctx, http.MethodPatch, "/api/senvanda/deploy/"+projectID+"/env", body, &envs)
+	if err != nil {
+		return err
+	}
+	if !redeploy {
+		if err := a.printEnv(raw, envs); err != nil {
+			return err
+		}
+		a.out.line("Saved. Run `senvanda deploy %s` (or pass --redeploy) to apply.", ref)
+		return nil
+	}
+	a.out.line("Saved.")
+	return a.queue(ctx, "/api/senvanda/deploy/"+projectID+"/action", map[string]string{"action": "redeploy"}, wait, timeout)
+}
+
+func (a *app) printEnv(raw []byte, envs []envVar) error {
+	if a.out.json() {
+		return a.out.raw(raw)
+	}
+	rows := make([][]string, 0, len(envs))
+	for _, e := range envs {
+		secret := ""
+		if e.Secret {
+			secret = "yes"
+		}
+		rows = append(rows, []string{e.Key, e.Value, secret})
+	}
+	return a.out.table([]string{"key", "value", "secret"}, rows)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) scanCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "scan <repo>",
+		Short:   "Detect the framework, port and env vars of a git repository",
+		Example: `  senvanda scan https://git.example.com/acme/shop.git`,
+		Args:    args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			var result struct {
+				Framework     string   `json:"framework"`
+				Version       string   `json:"version"`
+				StartCommand  string   `json:"startCommand"`
+				Port          int      `json:"port"`
+				Domain        string   `json:"domain"`
+				Name          string   `json:"name"`
+				Image         string   `json:"image"`
+				EnvVars       []envVar `json:"envVars"`
+				SecurityHints []string `json:"securityHints"`
+			}
+			raw, err := a.api.do(cmd.Context(), http.MethodPost, "/api/senvanda/deploy/scan", map[string]string{"url": argv[0]}, &result)
+			if err != nil {
+				return err
+			}
+			if a.out.json() {
+				return a.out.raw(raw)
+			}
+
+			rows := [][]string{
+				{"name", result.Name},
+				{"framework", strings.TrimSpace(result.Framework + " " + result.Version)},
+				{"image", result.Image},
+				{"port", strconv.Itoa(result.Port)},
+				{"start command", result.StartCommand},
+				{"domain", result.Domain},
+			}
+			for _, e := range result.EnvVars {
+				rows = append(rows, []string{"env", e.Key + "=" + e.Value})
+			}
+			for _, hint := range result.SecurityHints {
+				rows = append(rows, []string{"hint", hint})
+			}
+			return a.out.table([]string{"field", "value"}, rows)
+		},
+	}
+}
+
+func (a *app) adoptCommand() *cobra.Command {
+	var user string
+	cmd := &cobra.Command{
+		Use:   "adopt [container]",
+		Short: "List unmanaged containers, or adopt one as a project (admin)",
+		Example: `  senvanda adopt
+  senvanda adopt 3f2a9c1b7d4e --user <user id>`,
+		Args: args(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			if len(argv) == 0 {
+				var legacy []struct {
+					ID    string `json:"id"`
+					Name  string `json:"name"`
+					Image string `json:"image"`
+					State string `json:"state"`
+					Ports []int  `json:"ports"`
+				}
+				raw, err := a.api.do(cmd.Context(), http.MethodGet, "/api/senvanda/deploy/legacy", nil, &legacy)
+				if err != nil {
+					return err
+				}
+				if a.out.json() {
+					return a.out.raw(raw)
+				}
+				rows := make([][]string, 0, len(legacy))
+				for _, c := range legacy {
+					ports := make([]string, 0, len(c.Ports))
+					for _, p := range c.Ports {
+						ports = append(ports, strconv.Itoa(p))
+					}
+					rows = append(rows, []string{c.ID, c.Name, c.Image, c.State, strings.Join(ports, ",")})
+				}
+				return a.out.table([]string{"container", "name", "image", "state", "ports"}, rows)
+			}
+
+			var project struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			}
+			raw, err := a.api.do(cmd.Context(), http.MethodPost, "/api/senvanda/deploy/adopt", map[string]string{"containerID": argv[0], "user": user}, &project)
+			if err != nil {
+				return err
+			}
+			if a.out.json() {
+				return a.out.raw(raw)
+			}
+			a.out.line("Adopted %s as project %s (%s).", argv[0], project.Name, project.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&user, "user", "", "user id to own the adopted project (default: admin-managed)")
+	return cmd
+}
+
+func (a *app) pruneCommand() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete anonymous projects, stale drafts and projects whose container is gone (admin)",
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var result struct {
+				Pruned []struct {
+					ID     string `json:"id"`
+					Name   string `json:"name"`
+					Reason string `json:"reason"`
+				} `json:"pruned"`
+			}
+			path := "/api/senvanda/deploy/prune" + query(map[string]string{"dry_run": strconv.FormatBool(dryRun)})
+			raw, err := a.api.do(cmd.Context(), http.MethodPost, path, nil, &result)
+			if err != nil {
+				return err
+			}
+			if a.out.json() {
+				return a.out.raw(raw)
+			}
+
+			rows := make([][]string, 0, len(result.Pruned))
+			for _, p := range result.Pruned {
+				rows = append(rows, []string{p.ID, p.Name, p.Reason})
+			}
+			if err := a.out.table([]string{"id", "name", "reason"}, rows); err != nil {
+				return err
+			}
+			verb := "Deleted"
+			if dryRun {
+				verb = "Would delete"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %d project(s).\n", verb, len(result.Pruned))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list what would be deleted")
+	return cmd
+}
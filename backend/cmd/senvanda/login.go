@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func (a *app) loginCommand() *cobra.Command {
+	var (
+		email         string
+		admin         bool
+		passwordStdin bool
+	)
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Save credentials: a personal access token (--token) or an email/password session",
+		Long: `Save credentials for the next commands, in the user config directory (mode 0600).
+
+With --token the personal access token is checked and saved. Otherwise the command
+signs in with email and password (--admin for a PocketBase admin) and saves the
+session, which expires like a dashboard session does.`,
+		Example: `  senvanda login --url https://senvanda.example.com --token svd_...
+  senvanda login --email me@example.com
+  echo "$PASSWORD" | senvanda login --email ci@example.com --password-stdin`,
+		Args: args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			cfg := config{URL: a.api.baseURL}
+
+			if a.token != "" {
+				// A token without projects:read gets 403 here, which still proves it is valid
+				_, err := a.api.do(ctx, http.MethodGet, "/api/senvanda/deploy/projects", nil, nil)
+				var apiErr *apiError
+				if err != nil && !(errors.As(err, &apiErr) && apiErr.Status == http.StatusForbidden) {
+					return err
+				}
+				cfg.Token = a.token
+			} else {
+				stdin := bufio.NewReader(os.Stdin)
+				if email == "" {
+					if passwordStdin {
+						return &usageError{err: errors.New("--email is required with --password-stdin")}
+					}
+					fmt.Fprint(os.Stderr, "Email: ")
+					line, err := stdin.ReadString('\n')
+					if err != nil {
+						return err
+					}
+					email = strings.TrimSpace(line)
+				}
+				password, err := readPassword(stdin, passwordStdin)
+				if err != nil {
+					return err
+				}
+
+				endpoint := "/api/collections/users/auth-with-password"
+				if admin {
+					endpoint = "/api/admins/auth-with-password"
+				}
+				var session struct {
+					Token string `json:"token"`
+				}
+				anonymous := newClient(a.api.baseURL, "")
+				body := map[string]string{"identity": email, "password": password}
+				if _, err := anonymous.do(ctx, http.MethodPost, endpoint, body, &session); err != nil {
+					var apiErr *apiError
+					if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+						apiErr.Status = http.StatusUnauthorized // Wrong credentials, exit as an auth failure
+					}
+					return err
+				}
+				cfg.Token, cfg.Email, cfg.Admin = session.Token, email, admin
+			}
+
+			path, err := saveConfig(cfg)
+			if err != nil {
+				return err
+			}
+			who := "token"
+			if cfg.Email != "" {
+				who = cfg.Email
+			}
+			fmt.Fprintf(os.Stderr, "Logged in to %s as %s (saved in %s).\n", cfg.URL, who, path)
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&email, "email", "", "account email (prompted when empty)")
+	flags.BoolVar(&admin, "admin", false, "sign in as a PocketBase admin")
+	flags.BoolVar(&passwordStdin, "password-stdin", false, "read the password from stdin")
+	return cmd
+}
+
+// readPassword prompts without echo on a terminal, and reads one line otherwise
+func readPassword(stdin *bufio.Reader, fromStdin bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !fromStdin && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (a *app) logoutCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Forget the saved credentials",
+		Args:  args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg := a.cfg
+			cfg.Token, cfg.Email, cfg.Admin = "", "", false
+			if _, err := saveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "Logged out.")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// logLine is one `log` event of GET /deploy/:id/logs/stream
+type logLine struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container"`
+	Stream    string    `json:"stream"`
+	Text      string    `json:"text"`
+}
+
+func (a *app) logsCommand() *cobra.Command {
+	var (
+		follow                     bool
+		since, until, stream, grep string
+		tail, replica              int
+	)
+	cmd := &cobra.Command{
+		Use:   "logs <project>",
+		Short: "Print (and with -f follow) the logs of every replica",
+		Long: `Print the container logs of a project, every replica interleaved by time.
+In JSON mode each line is one JSON object (newline-delimited), so it can be piped to jq.`,
+		Example: `  senvanda logs shop -f
+  senvanda logs shop --since 1h --stream stderr --grep timeout`,
+		Args: args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			ctx := cmd.Context()
+			projectID, err := a.resolveProject(ctx, argv[0])
+			if err != nil {
+				return err
+			}
+
+			params := map[string]string{
+				"follow": strconv.FormatBool(follow),
+				"since":  since,
+				"until":  until,
+				"stream": stream,
+				"q":      grep,
+			}
+			if cmd.Flags().Changed("tail") {
+				params["tail"] = strconv.Itoa(tail)
+			}
+			if replica > 0 {
+				params["replica"] = strconv.Itoa(replica)
+			}
+
+			out := cmd.OutOrStdout()
+			return a.api.stream(ctx, "/api/senvanda/deploy/"+projectID+"/logs/stream"+query(params), func(e sseEvent) error {
+				if e.Name != "log" {
+					return nil
+				}
+				if a.out.json() {
+					_, err := fmt.Fprintf(out, "%s\n", e.Data)
+					return err
+				}
+				var line logLine
+				if err := json.Unmarshal(e.Data, &line); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintf(out, "%s %s %s\n", line.Time.Local().Format(time.RFC3339), line.Container, line.Text)
+				return err
+			})
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVarP(&follow, "follow", "f", false, "keep streaming new lines")
+	flags.StringVar(&since, "since", "", "start time: RFC 3339 or relative (15m, 2h)")
+	flags.StringVar(&until, "until", "", "end time: RFC 3339 or relative")
+	flags.IntVarP(&tail, "tail", "n", 0, "last N lines (100 unless --since is given, 0 = all)")
+	flags.StringVar(&stream, "stream", "", "stdout or stderr only")
+	flags.IntVar(&replica, "replica", 0, "one replica only")
+	flags.StringVar(&grep, "grep", "", "only lines containing this text")
+	return cmd
+}
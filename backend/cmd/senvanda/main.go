@@ -0,0 +1,182 @@
+// Command senvanda operates a Senvanda control plane from the shell or CI, through
+// the same /api/senvanda HTTP API the dashboard uses.
+//
+// Credentials come from --token / SENVANDA_TOKEN (a personal access token), or from
+// `senvanda login`, which stores a session in the user config directory.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes, stable for shell scripts and CI
+const (
+	exitOK          = 0
+	exitFailure     = 1 // The API refused or failed the operation
+	exitUsage       = 2 // Bad command line
+	exitAuth        = 3 // Not logged in, invalid token or missing permission
+	exitNotFound    = 4 // Project, job or deployment does not exist
+	exitJobFailed   = 5 // --wait: the deploy job failed or was superseded
+	exitUnreachable = 6 // No answer from the API
+)
+
+// usageError marks command-line mistakes
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+// jobError is a queued job that did not finish successfully
+type jobError struct {
+	id     string
+	status string
+	reason string
+}
+
+func (e *jobError) Error() string {
+	if e.reason != "" {
+		return fmt.Sprintf("job %s %s: %s", e.id, e.status, e.reason)
+	}
+	return fmt.Sprintf("job %s %s", e.id, e.status)
+}
+
+// exitCode maps an error to the documented exit codes
+func exitCode(err error) int {
+	var apiErr *apiError
+	var usageErr *usageError
+	var jobErr *jobError
+	var unreachable *unreachableError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &jobErr):
+		return exitJobFailed
+	case errors.As(err, &unreachable):
+		return exitUnreachable
+	case errors.As(err, &apiErr) && (apiErr.Status == 401 || apiErr.Status == 403):
+		return exitAuth
+	case errors.As(err, &apiErr) && apiErr.Status == 404:
+		return exitNotFound
+	case strings.HasPrefix(err.Error(), "unknown command"), strings.HasPrefix(err.Error(), "unknown flag"):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// app holds the global flags and what the commands share
+type app struct {
+	url    string
+	token  string
+	output string
+
+	cfg config
+	api *client
+	out *printer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{}
+	root := a.rootCommand()
+	err := root.ExecuteContext(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+func (a *app) rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "senvanda",
+		Short: "Command-line client for the Senvanda control plane",
+		Long: `Command-line client for the Senvanda control plane.
+
+Authentication: --token or SENVANDA_TOKEN (personal access token, svd_...), or the
+session saved by "senvanda login". The API address comes from --url, SENVANDA_URL or
+the saved login (default ` + defaultURL + `).
+
+Exit codes:
+  0  success
+  1  the API refused or failed the operation
+  2  bad command line
+  3  not logged in, invalid token or missing permission
+  4  project, job or deployment not found
+  5  --wait: the job failed or was superseded
+  6  the API could not be reached`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PersistentPreRunE: a.setup,
+	}
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.url, "url", "", "control plane URL (env SENVANDA_URL)")
+	flags.StringVar(&a.token, "token", "", "personal access token (env SENVANDA_TOKEN)")
+	flags.StringVarP(&a.output, "output", "o", outputTable, "output format: table or json")
+
+	root.AddCommand(
+		a.loginCommand(),
+		a.logoutCommand(),
+		a.projectsCommand(),
+		a.deployCommand(),
+		a.logsCommand(),
+		a.envCommand(),
+		a.rollbackCommand(),
+		a.scanCommand(),
+		a.adoptCommand(),
+		a.pruneCommand(),
+	)
+	return root
+}
+
+// setup resolves the API address, credentials and output mode: flags first, then the
+// environment, then the saved login
+func (a *app) setup(cmd *cobra.Command, _ []string) error {
+	if a.output != outputTable && a.output != outputJSON {
+		return &usageError{err: fmt.Errorf("invalid output %q (use table or json)", a.output)}
+	}
+	a.out = &printer{mode: a.output, out: cmd.OutOrStdout()}
+
+	var err error
+	if a.cfg, err = loadConfig(); err != nil {
+		return err
+	}
+	url := firstNonEmpty(a.url, os.Getenv("SENVANDA_URL"), a.cfg.URL, defaultURL)
+	token := firstNonEmpty(a.token, os.Getenv("SENVANDA_TOKEN"), a.cfg.Token)
+	a.api = newClient(url, token)
+	return nil
+}
+
+// args wraps a cobra argument check so its errors exit with exitUsage
+func args(check cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := check(cmd, args); err != nil {
+			return &usageError{err: err}
+		}
+		return nil
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output modes
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes command results as a table for people or as JSON for scripts
+type printer struct {
+	mode string
+	out  io.Writer
+}
+
+func (p *printer) json() bool {
+	return p.mode == outputJSON
+}
+
+// raw prints an API answer unchanged (indented) in JSON mode
+func (p *printer) raw(body json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(body), "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := p.out.Write(buf.Bytes())
+	return err
+}
+
+// table prints rows under an upper-case header, columns aligned
+func (p *printer) table(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		for i, cell := range row {
+			if cell == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// line prints a message in table mode only; JSON mode keeps stdout parseable
+func (p *printer) line(format string, args ...any) {
+	if !p.json() {
+		fmt.Fprintf(p.out, format+"\n", args...)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// projectIDPattern matches PocketBase record ids; anything else is looked up by name
+var projectIDPattern = regexp.MustCompile(`^[a-z0-9]{15}$`)
+
+// projectView is the part of GET /deploy/projects the CLI shows
+type projectView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	DBStatus string `json:"db_status"`
+	Status   string `json:"status"`
+	State    string `json:"state"`
+	Image    string `json:"image"`
+	Team     string `json:"team"`
+	Role     string `json:"role"`
+}
+
+// jobView is GET /jobs/:jobId
+type jobView struct {
+	ID           string `json:"id"`
+	Kind         string `json:"kind"`
+	Status       string `json:"status"`
+	Error        string `json:"error"`
+	SupersededBy string `json:"superseded_by"`
+	DeploymentID string `json:"deployment_id"`
+	Position     int    `json:"position"`
+}
+
+// queuedView is the answer of the endpoints that enqueue a job
+type queuedView struct {
+	JobID      string `json:"job_id"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	RollbackOf string `json:"rollback_of"`
+}
+
+// resolveProject accepts a project id or name
+func (a *app) resolveProject(ctx context.Context, ref string) (string, error) {
+	if projectIDPattern.MatchString(ref) {
+		return ref, nil
+	}
+	var projects []projectView
+	if _, err := a.api.do(ctx, http.MethodGet, "/api/senvanda/deploy/projects", nil, &projects); err != nil {
+		return "", err
+	}
+	for _, p := range projects {
+		if p.Name == ref {
+			return p.ID, nil
+		}
+	}
+	return "", &apiError{Status: http.StatusNotFound, Message: fmt.Sprintf("project %q not found", ref)}
+}
+
+func (a *app) projectsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "projects",
+		Aliases: []string{"project"},
+		Short:   "Inspect projects",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the projects you can access",
+		Args:    args(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var projects []projectView
+			raw, err := a.api.do(cmd.Context(), http.MethodGet, "/api/senvanda/deploy/projects", nil, &projects)
+			if err != nil {
+				return err
+			}
+			if a.out.json() {
+				return a.out.raw(raw)
+			}
+			rows := make([][]string, 0, len(projects))
+			for _, p := range projects {
+				rows = append(rows, []string{p.ID, p.Name, firstNonEmpty(p.DBStatus, p.Status), p.State, p.Role, p.Team, p.Image})
+			}
+			return a.out.table([]string{"id", "name", "status", "state", "role", "team", "image"}, rows)
+		},
+	})
+	return cmd
+}
+
+func (a *app) deployCommand() *cobra.Command {
+	var (
+		name, repo, image, team string
+		port                    int
+		draft, wait             bool
+		timeout                 time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "deploy [project]",
+		Short: "Redeploy a project, or create one with --name",
+		Long: `Redeploy an existing project (by id or name), or create and deploy a new one
+with --name and --repo or --image. With --wait the command follows the deploy job and
+exits 5 if it fails.`,
+		Example: `  senvanda deploy shop --wait
+  senvanda deploy --name shop --repo https://git.example.com/acme/shop.git --port 3000`,
+		Args: args(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			ctx := cmd.Context()
+			if len(argv) == 1 && name != "" {
+				return &usageError{err: fmt.Errorf("give either a project or --name, not both")}
+			}
+			if len(argv) == 0 && name == "" {
+				return &usageError{err: fmt.Errorf("a project or --name is required")}
+			}
+
+			if name != "" {
+				endpoint := "/api/senvanda/deploy/create"
+				if draft {
+					endpoint = "/api/senvanda/deploy/draft"
+				}
+				var project struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				}
+				body := map[string]any{"name": name, "repoUrl": repo, "image": image, "port": port, "team": team}
+				raw, err := a.api.do(ctx, http.MethodPost, endpoint, body, &project)
+				if err != nil {
+					return err
+				}
+				a.out.line("Project %s created (%s)", project.Name, project.ID)
+				if draft || !wait {
+					if a.out.json() {
+						return a.out.raw(raw)
+					}
+					return nil
+				}
+				jobID, err := a.latestJob(ctx, project.ID)
+				if err != nil {
+					return err
+				}
+				return a.waitJob(ctx, jobID, timeout)
+			}
+
+			projectID, err := a.resolveProject(ctx, argv[0])
+			if err != nil {
+				return err
+			}
+			return a.queue(ctx, "/api/senvanda/deploy/"+projectID+"/action", map[string]string{"action": "redeploy"}, wait, timeout)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&name, "name", "", "create a new project with this name")
+	flags.StringVar(&repo, "repo", "", "git repository of the new project")
+	flags.StringVar(&image, "image", "", "container image of the new project")
+	flags.IntVar(&port, "port", 0, "port the new project listens on (default 80)")
+	flags.StringVar(&team, "team", "", "team the new project belongs to")
+	flags.BoolVar(&draft, "draft", false, "create the project without deploying it")
+	addWaitFlags(cmd, &wait, &timeout)
+	return cmd
+}
+
+func (a *app) rollbackCommand() *cobra.Command {
+	var (
+		to      string
+		wait    bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "rollback <project>",
+		Short: "Restore the previous successful release (or --to a given deployment)",
+		Args:  args(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, argv []string) error {
+			projectID, err := a.resolveProject(cmd.Context(), argv[0])
+			if err != nil {
+				return err
+			}
+			return a.queue(cmd.Context(), "/api/senvanda/deploy/"+projectID+"/rollback", map[string]string{"deployment_id": to}, wait, timeout)
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "", "deployment id to restore")
+	addWaitFlags(cmd, &wait, &timeout)
+	return cmd
+}
+
+func addWaitFlags(cmd *cobra.Command, wait *bool, timeout *time.Duration) {
+	cmd.Flags().BoolVarP(wait, "wait", "w", false, "wait for the job to finish")
+	cmd.Flags().DurationVar(timeout, "timeout", 15*time.Minute, "how long --wait waits")
+}
+
+// queue posts to an endpoint that enqueues a job, then optionally waits for it
+func (a *app) queue(ctx context.Context, path string, body any, wait bool, timeout time.Duration) error {
+	var queued queuedView
+	raw, err := a.api.do(ctx, http.MethodPost, path, body, &queued)
+	if err != nil {
+		return err
+	}
+	if !wait {
+		if a.out.json() {
+			return a.out.raw(raw)
+		}
+		a.out.line("%s (job %s)", strings.TrimSuffix(queued.Message, "."), queued.JobID)
+		return nil
+	}
+	return a.waitJob(ctx, queued.JobID, timeout)
+}
+
+// latestJob returns the newest job of a project
+func (a *app) latestJob(ctx context.Context, projectID string) (string, error) {
+	var jobs []jobView
+	if _, err := a.api.do(ctx, http.MethodGet, "/api/senvanda/deploy/"+projectID+"/jobs?limit=1", nil, &jobs); err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "", fmt.Errorf("no job was queued for project %s", projectID)
+	}
+	return jobs[0].ID, nil
+}
+
+// waitJob polls a job until it is done, failed or superseded. Progress goes to
+// stderr so stdout only carries the result.
+func (a *app) waitJob(ctx context.Context, jobID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		var job jobView
+		raw, err := a.api.do(ctx, http.MethodGet, "/api/senvanda/jobs/"+jobID, nil, &job)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("job %s still %s after %s", jobID, last, timeout)
+		}
+		if err != nil {
+			return err
+		}
+		if job.Status != last {
+			progress := job.Status
+			if job.Position > 0 {
+				progress += fmt.Sprintf(" (%d ahead)", job.Position)
+			}
+			fmt.Fprintf(os.Stderr, "%s job %s: %s\n", job.Kind, job.ID, progress)
+			last = job.Status
+		}
+
+		switch job.Status {
+		case "done":
+			if a.out.json() {
+				return a.out.raw(raw)
+			}
+			if job.DeploymentID != "" {
+				a.out.line("Deployment %s is live.", job.DeploymentID)
+			} else {
+				a.out.line("Job %s done.", job.ID)
+			}
+			return nil
+		case "failed":
+			if a.out.json() {
+				_ = a.out.raw(raw)
+			}
+			return &jobError{id: job.ID, status: job.Status, reason: strings.TrimSpace(job.Error)}
+		case "superseded":
+			if a.out.json() {
+				_ = a.out.raw(raw)
+			}
+			return &jobError{id: job.ID, status: job.Status, reason: "replaced by job " + job.SupersededBy}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s still %s after %s", jobID, last, timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/senvanda/backend/internal/auth"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+// Handler exposes the audit log
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new audit handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the audit endpoints. The full log is for admins; project
+// members see the events of their project.
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	adminOnly := auth.RequireAdmin()
+	g.GET("/audit", h.handleList, adminOnly)
+	g.GET("/audit/export", h.handleExport, adminOnly)
+	g.GET("/deploy/:id/audit", h.handleProjectList)
+}
+
+type pageView struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	TotalItems int    `json:"totalItems"`
+	Items      []View `json:"items"`
+}
+
+// handleList returns a page of events, newest first
+// URL: GET /api/senvanda/audit?actor_type=user&actor=a@b.c&action=project.&project=&outcome=failure&since=2024-01-01&until=&page=1&perPage=50
+func (h *Handler) handleList(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	return h.respond(c, filter)
+}
+
+// handleProjectList returns the events of one project
+// URL: GET /api/senvanda/deploy/:id/audit?action=&outcome=&page=1
+func (h *Handler) handleProjectList(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	filter.Project = c.PathParam("id")
+	return h.respond(c, filter)
+}
+
+func (h *Handler) respond(c echo.Context, filter Filter) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.QueryParam("perPage"))
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	perPage = min(perPage, maxPerPage)
+
+	records, total, err := h.service.Find(filter, page, perPage)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to read audit log", err)
+	}
+	items := make([]View, 0, len(records))
+	for _, r := range records {
+		items = append(items, ToView(r))
+	}
+	return c.JSON(http.StatusOK, pageView{Page: page, PerPage: perPage, TotalItems: total, Items: items})
+}
+
+// handleExport streams every matching event as JSON lines, oldest first
+// URL: GET /api/senvanda/audit/export?since=2024-01-01
+func (h *Handler) handleExport(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="audit-%s.jsonl"`, time.Now().UTC().Format("20060102-150405")))
+	res.WriteHeader(http.StatusOK)
+	return h.service.Export(res, filter)
+}
+
+// parseFilter reads the filter from the query. Dates accept RFC 3339 or YYYY-MM-DD.
+func parseFilter(c echo.Context) (Filter, error) {
+	filter := Filter{
+		ActorType: c.QueryParam("actor_type"),
+		Actor:     c.QueryParam("actor"),
+		Action:    c.QueryParam("action"),
+		Project:   c.QueryParam("project"),
+		Outcome:   c.QueryParam("outcome"),
+	}
+	var err error
+	if filter.Since, err = parseTime(c.QueryParam("since")); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseTime(c.QueryParam("until")); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseTime(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(types.DefaultDateLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q (use RFC 3339 or YYYY-MM-DD)", value)
+}
@@ -0,0 +1,337 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Who performed an action
+const (
+	ActorUser      = "user"
+	ActorAdmin     = "admin"
+	ActorWebhook   = "webhook"   // Project webhook token (Gitea push, token redeploy)
+	ActorCI        = "ci"        // Shared CI secret (Woodpecker)
+	ActorAnonymous = "anonymous" // Rejected before anyone was identified
+)
+
+// Outcomes of an action. They describe the request, not what it started: a deploy,
+// rollback or scale that was accepted is "queued", and its `job` param points at the
+// deploy_jobs record that tells whether it succeeded.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeQueued  = "queued"
+)
+
+// Params that are never stored (credentials passed in the URL)
+var redacted = map[string]bool{"token": true, "secret": true, "password": true}
+
+const contextKey = "senvanda.audit"
+
+// entry is filled while a request runs and saved when it returns
+type entry struct {
+	force     bool // Record even though the method does not change anything
+	action    string
+	actorType string
+	actorID   string
+	actor     string
+	project   string
+	queued    bool // The request queued a job (see Queued)
+	params    map[string]any
+}
+
+func current(c echo.Context) *entry {
+	e, _ := c.Get(contextKey).(*entry)
+	return e
+}
+
+// SetAction names the action of the current request. Named requests are recorded
+// even when they are reads (e.g. opening a terminal).
+func SetAction(c echo.Context, action string) {
+	if e := current(c); e != nil {
+		e.action = action
+		e.force = true
+	}
+}
+
+// SetActor identifies callers that do not sign in (webhook token, CI secret)
+func SetActor(c echo.Context, actorType string, actorID string, label string) {
+	if e := current(c); e != nil {
+		e.actorType, e.actorID, e.actor = actorType, actorID, label
+	}
+}
+
+// SetProject sets the target project of routes without `:id`
+func SetProject(c echo.Context, projectID string) {
+	if e := current(c); e != nil {
+		e.project = projectID
+	}
+}
+
+// Queued marks the request as having queued a deploy job and records its ID
+func Queued(c echo.Context, jobID string) {
+	if e := current(c); e != nil {
+		e.queued = true
+		e.params["job"] = jobID
+	}
+}
+
+// Param adds a parameter worth keeping (never a secret)
+func Param(c echo.Context, key string, value any) {
+	if e := current(c); e != nil {
+		e.params[key] = value
+	}
+}
+
+// Service records control-plane actions in the append-only `audit_events` collection
+type Service struct {
+	app core.App
+}
+
+// NewService creates a new audit service
+func NewService(app core.App) *Service {
+	return &Service{app: app}
+}
+
+// BindHooks makes `audit_events` append-only through the records API, for admins too.
+// The service itself writes through the Dao, which the hooks do not cover.
+func (s *Service) BindHooks() {
+	refuse := apis.NewForbiddenError("Audit events are append-only.", nil)
+	s.app.OnRecordBeforeCreateRequest("audit_events").Add(func(e *core.RecordCreateEvent) error { return refuse })
+	s.app.OnRecordBeforeUpdateRequest("audit_events").Add(func(e *core.RecordUpdateEvent) error { return refuse })
+	s.app.OnRecordBeforeDeleteRequest("audit_events").Add(func(e *core.RecordDeleteEvent) error { return refuse })
+}
+
+// Middleware records every request that changes something (and reads named with
+// SetAction) once the handler returns, with its outcome
+func (s *Service) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			e := &entry{params: make(map[string]any)}
+			c.Set(contextKey, e)
+
+			err := next(c)
+
+			method := c.Request().Method
+			if !e.force && (method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions) {
+				return err
+			}
+			s.save(c, e, err)
+			return err
+		}
+	}
+}
+
+func (s *Service) save(c echo.Context, e *entry, handlerErr error) {
+	collection, err := s.app.Dao().FindCollectionByNameOrId("audit_events")
+	if err != nil {
+		log.Printf("⚠️ Audit log unavailable: %v", err)
+		return
+	}
+
+	route := strings.TrimPrefix(c.Path(), "/api/senvanda")
+	if e.action == "" {
+		e.action = c.Request().Method + " " + route
+	}
+	if e.actorType == "" {
+		e.actorType = ActorAnonymous
+		if admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin); admin != nil {
+			e.actorType, e.actorID, e.actor = ActorAdmin, admin.Id, admin.Email
+		} else if user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record); user != nil {
+			e.actorType, e.actorID, e.actor = ActorUser, user.Id, user.Email()
+		}
+	}
+	if e.project == "" {
+		e.project = c.PathParam("id")
+	}
+
+	// Path and query parameters, minus credentials; handler params win
+	params := make(map[string]any)
+	for _, name := range c.PathParams() {
+		if name.Name != "id" && !redacted[name.Name] {
+			params[name.Name] = name.Value
+		}
+	}
+	for key, values := range c.QueryParams() {
+		if !redacted[key] && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	for key, value := range e.params {
+		params[key] = value
+	}
+
+	status, outcome, message := c.Response().Status, OutcomeSuccess, ""
+	if handlerErr != nil {
+		status, message = http.StatusInternalServerError, handlerErr.Error()
+		var apiErr *apis.ApiError
+		var httpErr *echo.HTTPError
+		if errors.As(handlerErr, &apiErr) {
+			status, message = apiErr.Code, apiErr.Message
+		} else if errors.As(handlerErr, &httpErr) {
+			status = httpErr.Code
+		}
+	}
+	if status >= 400 {
+		outcome = OutcomeFailure
+	} else if e.queued {
+		outcome = OutcomeQueued
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("actor_type", e.actorType)
+	record.Set("actor_id", e.actorID)
+	record.Set("actor", e.actor)
+	record.Set("action", e.action)
+	record.Set("project", e.project)
+	record.Set("method", c.Request().Method)
+	record.Set("route", route)
+	record.Set("ip", c.RealIP())
+	record.Set("params", params)
+	record.Set("status", status)
+	record.Set("outcome", outcome)
+	record.Set("error", message)
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to write audit event %s: %v", e.action, err)
+	}
+}
+
+// Filter narrows the events returned by Find and Export. Empty fields match everything.
+type Filter struct {
+	ActorType string
+	Actor     string // Actor id or label (email)
+	Action    string // Prefix: "project." matches every project action
+	Project   string
+	Outcome   string
+	Since     string // Inclusive, PocketBase date format
+	Until     string // Exclusive
+}
+
+func (f Filter) where() dbx.Expression {
+	var exps []dbx.Expression
+	if f.ActorType != "" {
+		exps = append(exps, dbx.HashExp{"actor_type": f.ActorType})
+	}
+	if f.Actor != "" {
+		exps = append(exps, dbx.Or(dbx.HashExp{"actor_id": f.Actor}, dbx.HashExp{"actor": f.Actor}))
+	}
+	if f.Action != "" {
+		exps = append(exps, dbx.Like("action", f.Action).Match(false, true))
+	}
+	if f.Project != "" {
+		exps = append(exps, dbx.HashExp{"project": f.Project})
+	}
+	if f.Outcome != "" {
+		exps = append(exps, dbx.HashExp{"outcome": f.Outcome})
+	}
+	if f.Since != "" {
+		exps = append(exps, dbx.NewExp("created >= {:since}", dbx.Params{"since": f.Since}))
+	}
+	if f.Until != "" {
+		exps = append(exps, dbx.NewExp("created < {:until}", dbx.Params{"until": f.Until}))
+	}
+	if len(exps) == 0 {
+		return nil
+	}
+	return dbx.And(exps...)
+}
+
+// query selects the matching events (dbx renders an empty And as "()", so it is left out)
+func (s *Service) query(filter Filter) *dbx.SelectQuery {
+	q := s.app.Dao().RecordQuery("audit_events")
+	if where := filter.where(); where != nil {
+		q.AndWhere(where)
+	}
+	return q
+}
+
+// Find returns one page of matching events, newest first, and the number of matches
+func (s *Service) Find(filter Filter, page int, perPage int) ([]*models.Record, int, error) {
+	var total int
+	err := s.query(filter).Select("count(*)").Row(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := []*models.Record{}
+	err = s.query(filter).
+		OrderBy("created DESC", "id DESC").
+		Offset(int64((page - 1) * perPage)).
+		Limit(int64(perPage)).
+		All(&records)
+	return records, total, err
+}
+
+// Export writes every matching event as a JSON line, oldest first, in batches
+func (s *Service) Export(w io.Writer, filter Filter) error {
+	const batch = 500
+	encoder := json.NewEncoder(w)
+	for offset := 0; ; offset += batch {
+		records := []*models.Record{}
+		err := s.query(filter).
+			OrderBy("created ASC", "id ASC").
+			Offset(int64(offset)).
+			Limit(batch).
+			All(&records)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := encoder.Encode(ToView(r)); err != nil {
+				return err
+			}
+		}
+		if len(records) < batch {
+			return nil
+		}
+	}
+}
+
+// View is the API and export shape of an event
+type View struct {
+	ID        string         `json:"id"`
+	Time      string         `json:"time"`
+	ActorType string         `json:"actor_type"`
+	ActorID   string         `json:"actor_id,omitempty"`
+	Actor     string         `json:"actor,omitempty"`
+	Action    string         `json:"action"`
+	Project   string         `json:"project,omitempty"`
+	Method    string         `json:"method"`
+	Route     string         `json:"route"`
+	IP        string         `json:"ip"`
+	Params    map[string]any `json:"params,omitempty"`
+	Status    int            `json:"status"`
+	Outcome   string         `json:"outcome"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// ToView converts an `audit_events` record
+func ToView(r *models.Record) View {
+	view := View{
+		ID:        r.Id,
+		Time:      r.Created.String(),
+		ActorType: r.GetString("actor_type"),
+		ActorID:   r.GetString("actor_id"),
+		Actor:     r.GetString("actor"),
+		Action:    r.GetString("action"),
+		Project:   r.GetString("project"),
+		Method:    r.GetString("method"),
+		Route:     r.GetString("route"),
+		IP:        r.GetString("ip"),
+		Status:    r.GetInt("status"),
+		Outcome:   r.GetString("outcome"),
+		Error:     r.GetString("error"),
+	}
+	_ = r.UnmarshalJSONField("params", &view.Params)
+	return view
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// ErrForbidden is returned when the caller has no role on the project
+var ErrForbidden = errors.New("you do not have access to this project")
+
+// User returns the signed-in users record, nil for admins and anonymous callers
+func User(c echo.Context) *models.Record {
+	user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
+	return user
+}
+
+// UserID returns the id of the signed-in user, "" for admins and anonymous callers
+func UserID(c echo.Context) string {
+	if user := User(c); user != nil {
+		return user.Id
+	}
+	return ""
+}
+
+// IsAdmin reports whether the caller is a PocketBase admin
+func IsAdmin(c echo.Context) bool {
+	admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
+	return admin != nil
+}
+
+// Roles on a project, from least to most privileged. Viewers can read (status,
+// logs, metrics), developers can also deploy and change settings, owners can
+// additionally manage access (teams, terminal).
+const (
+	RoleViewer    = "viewer"
+	RoleDeveloper = "developer"
+	RoleOwner     = "owner"
+)
+
+const contextRoleKey = "senvanda.projectRole"
+
+var roleRank = map[string]int{
+	RoleViewer:    1,
+	RoleDeveloper: 2,
+	RoleOwner:     3,
+}
+
+// ValidRole reports whether role is one of the team roles
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// HasRole reports whether role grants at least min. The empty role grants nothing.
+func HasRole(role string, min string) bool {
+	return roleRank[role] > 0 && roleRank[role] >= roleRank[min]
+}
+
+// TeamRole returns the user's role in the team, "" when not a member
+func TeamRole(app core.App, teamID string, userID string) string {
+	if teamID == "" || userID == "" {
+		return ""
+	}
+	member, err := app.Dao().FindFirstRecordByFilter(
+		"team_members", "team = {:team} && user = {:user}", dbx.Params{"team": teamID, "user": userID},
+	)
+	if err != nil {
+		return ""
+	}
+	return member.GetString("role")
+}
+
+// TeamIDs returns the teams the user is a member of
+func TeamIDs(app core.App, userID string) []string {
+	members, err := app.Dao().FindRecordsByFilter("team_members", "user = {:user}", "", 0, 0, dbx.Params{"user": userID})
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.GetString("team"))
+	}
+	return ids
+}
+
+// ProjectRole returns the caller's role on the project: owner for admins and the
+// project's personal owner, the team role for members of the project's team, "" otherwise.
+// Previews carry their parent's owner and team.
+func ProjectRole(app core.App, c echo.Context, project *models.Record) string {
+	if IsAdmin(c) {
+		return RoleOwner
+	}
+	userID := UserID(c)
+	if userID == "" {
+		return ""
+	}
+	if project.GetString("user") == userID {
+		return RoleOwner
+	}
+	return TeamRole(app, project.GetString("team"), userID)
+}
+
+// CanAccess reports whether the caller may see the project (any role)
+func CanAccess(app core.App, c echo.Context, project *models.Record) bool {
+	return ProjectRole(app, c, project) != ""
+}
+
+// CanAccessProject is CanAccess by project id. Unknown projects are not accessible.
+func CanAccessProject(app core.App, c echo.Context, projectID string) bool {
+	project, err := app.Dao().FindRecordById("projects", projectID)
+	return err == nil && CanAccess(app, c, project)
+}
+
+// RoleFromContext returns the role RequireProjectAccess resolved for the `:id` project
+func RoleFromContext(c echo.Context) string {
+	role, _ := c.Get(contextRoleKey).(string)
+	return role
+}
+
+// loadQueryToken authenticates `?token=` for clients that cannot set headers
+// (EventSource, WebSocket). The Authorization header wins when both are present.
+func loadQueryToken(app core.App, c echo.Context) {
+	token := c.QueryParam("token")
+	if token == "" || User(c) != nil || IsAdmin(c) {
+		return
+	}
+	if user, err := app.Dao().FindAuthRecordByToken(token, app.Settings().RecordAuthToken.Secret); err == nil {
+		c.Set(apis.ContextAuthRecordKey, user)
+		return
+	}
+	if admin, err := app.Dao().FindAdminByToken(token, app.Settings().AdminAuthToken.Secret); err == nil {
+		c.Set(apis.ContextAdminKey, admin)
+	}
+}
+
+// RequireAuth rejects requests without a signed-in user or admin
+func RequireAuth(app core.App) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			loadQueryToken(app, c)
+			if User(c) == nil && !IsAdmin(c) {
+				return apis.NewUnauthorizedError("The request requires a signed-in user or admin.", nil)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireAdmin rejects everyone but admins (host-wide operations)
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !IsAdmin(c) {
+				return apis.NewForbiddenError("Only admins can perform this action.", nil)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireProjectAccess checks the project of every `:id` route against the caller's
+// role: reads (GET, HEAD) need viewer, everything else developer. Handlers needing
+// more check RoleFromContext. Routes without `:id` pass through. Must run after RequireAuth.
+func RequireProjectAccess(app core.App) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			projectID := c.PathParam("id")
+			if projectID == "" {
+				return next(c)
+			}
+			project, err := app.Dao().FindRecordById("projects", projectID)
+			if err != nil {
+				return apis.NewNotFoundError("Project not found", err)
+			}
+
+			role := ProjectRole(app, c, project)
+			if role == "" {
+				return apis.NewForbiddenError(ErrForbidden.Error(), nil)
+			}
+			required := RoleDeveloper
+			if m := c.Request().Method; m == http.MethodGet || m == http.MethodHead {
+				required = RoleViewer
+			}
+			if !HasRole(role, required) {
+				return apis.NewForbiddenError(fmt.Sprintf("Your role (%s) does not allow this action.", role), nil)
+			}
+			c.Set(contextRoleKey, role)
+			return next(c)
+		}
+	}
+}
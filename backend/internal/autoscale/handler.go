@@ -0,0 +1,88 @@
+package autoscale
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// Handler exposes the autoscaler configuration and decision log
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new autoscale handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the autoscale endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/autoscale", h.handleGetAutoscale)
+}
+
+type decisionView struct {
+	ID             string  `json:"id"`
+	Decision       string  `json:"decision"`
+	Replicas       int     `json:"replicas"`
+	TargetReplicas int     `json:"target_replicas"`
+	CPUPercent     float64 `json:"cpu_percent"`
+	MemoryPercent  float64 `json:"memory_percent"`
+	Reason         string  `json:"reason"`
+	JobID          string  `json:"job_id,omitempty"`
+	Created        string  `json:"created"`
+}
+
+type autoscaleView struct {
+	Enabled   bool                    `json:"enabled"`
+	Config    *orchestrator.Autoscale `json:"config,omitempty"`
+	Decisions []decisionView          `json:"decisions"`
+}
+
+// handleGetAutoscale returns the effective config and the latest decisions
+// URL: GET /api/senvanda/deploy/:id/autoscale?limit=50
+func (h *Handler) handleGetAutoscale(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	spec, err := orchestrator.SpecFromProject(project)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	records, err := h.service.Decisions(project.Id, limit)
+	if err != nil {
+		return apis.NewNotFoundError("Failed to list autoscale decisions", err)
+	}
+
+	view := autoscaleView{
+		Enabled:   spec.Autoscale != nil,
+		Config:    spec.Autoscale,
+		Decisions: make([]decisionView, 0, len(records)),
+	}
+	for _, r := range records {
+		view.Decisions = append(view.Decisions, decisionView{
+			ID:             r.Id,
+			Decision:       r.GetString("decision"),
+			Replicas:       r.GetInt("replicas"),
+			TargetReplicas: r.GetInt("target_replicas"),
+			CPUPercent:     r.GetFloat("cpu_percent"),
+			MemoryPercent:  r.GetFloat("memory_percent"),
+			Reason:         r.GetString("reason"),
+			JobID:          r.GetString("job"),
+			Created:        r.GetString("created"),
+		})
+	}
+
+	return c.JSON(200, view)
+}
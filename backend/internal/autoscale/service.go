@@ -0,0 +1,257 @@
+package autoscale
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/queue"
+)
+
+// Decision values stored in `autoscale_decisions`
+const (
+	DecisionScaleUp   = "scale_up"
+	DecisionScaleDown = "scale_down"
+	DecisionCooldown  = "cooldown" // Scaling was due but the last change is too recent
+	DecisionQuota     = "quota"    // Scaling up was due but the owner's quota is used up
+)
+
+// Sample is the average usage across a project's replicas
+type Sample struct {
+	CPUPercent    float64 // Percent of the CPU limit
+	MemoryPercent float64 // Percent of the memory limit
+	Replicas      int
+}
+
+// Service periodically samples Docker stats of autoscaled projects and
+// queues scale jobs when usage crosses the configured thresholds
+type Service struct {
+	app          core.App
+	dockerClient *docker.Client
+	orchestrator *orchestrator.Service
+	jobs         *queue.Service
+	interval     time.Duration
+}
+
+// NewService creates the autoscaler. interval <= 0 means 30s.
+func NewService(app core.App, dockerClient *docker.Client, orchestratorSvc *orchestrator.Service, jobs *queue.Service, interval time.Duration) *Service {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Service{
+		app:          app,
+		dockerClient: dockerClient,
+		orchestrator: orchestratorSvc,
+		jobs:         jobs,
+		interval:     interval,
+	}
+}
+
+// Start runs the evaluation loop in the background
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.tick()
+		}
+	}()
+}
+
+func (s *Service) tick() {
+	projects, err := s.app.Dao().FindRecordsByFilter("projects", "status = 'online'", "", 0, 0)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	for _, project := range projects {
+		if err := s.evaluate(ctx, project); err != nil {
+			log.Printf("⚠️ Autoscale %s: %v", project.GetString("name"), err)
+		}
+	}
+}
+
+// evaluate samples one project and queues a scale job when needed
+func (s *Service) evaluate(ctx context.Context, project *models.Record) error {
+	spec, err := orchestrator.SpecFromProject(project)
+	if err != nil || spec.Autoscale == nil {
+		return nil
+	}
+	// A deploy or scale in flight changes the replica set under our feet
+	if s.jobs.HasPending(project.Id) {
+		return nil
+	}
+
+	names, err := s.orchestrator.ReplicaNames(ctx, project, spec)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+
+	sample, err := s.sample(ctx, names, spec)
+	if err != nil {
+		return err
+	}
+	target, reason := decide(*spec.Autoscale, sample)
+	if target == sample.Replicas {
+		return nil
+	}
+
+	decision := DecisionScaleUp
+	cooldown := time.Duration(spec.Autoscale.ScaleUpCooldown)
+	if target < sample.Replicas {
+		decision = DecisionScaleDown
+		cooldown = time.Duration(spec.Autoscale.ScaleDownCooldown)
+	}
+
+	if last := s.lastChange(project.Id); !last.IsZero() && time.Since(last) < cooldown {
+		remaining := (cooldown - time.Since(last)).Round(time.Second)
+		s.record(project, DecisionCooldown, target, sample, fmt.Sprintf("%s, cooling down for %s", reason, remaining), "")
+		return nil
+	}
+
+	if decision == DecisionScaleUp {
+		scaled := spec
+		scaled.Replicas = target
+		if err := s.orchestrator.Admit(project, scaled); err != nil {
+			s.record(project, DecisionQuota, target, sample, fmt.Sprintf("%s, %v", reason, err), "")
+			return nil
+		}
+	}
+
+	log.Printf("📐 Autoscale %s: %d -> %d replicas (%s)", spec.Name, sample.Replicas, target, reason)
+	if err := s.orchestrator.SetReplicas(project, target); err != nil {
+		return err
+	}
+	job, err := s.jobs.Enqueue(project.Id, queue.KindScale, history.TriggerAutoscale, map[string]any{
+		"replicas": target,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.record(project, decision, target, sample, reason, job.Id)
+	return nil
+}
+
+// decide returns the desired replica count: one step at a time within the bounds.
+// Any metric over its scale-up threshold scales up; scaling down needs all metrics low.
+func decide(cfg orchestrator.Autoscale, sample Sample) (int, string) {
+	current := sample.Replicas
+	if current < cfg.MinReplicas {
+		return cfg.MinReplicas, fmt.Sprintf("below minReplicas (%d)", cfg.MinReplicas)
+	}
+	if current > cfg.MaxReplicas {
+		return cfg.MaxReplicas, fmt.Sprintf("above maxReplicas (%d)", cfg.MaxReplicas)
+	}
+
+	if cfg.CPU != nil && sample.CPUPercent >= cfg.CPU.ScaleUp && current < cfg.MaxReplicas {
+		return current + 1, fmt.Sprintf("CPU %.1f%% >= %.0f%%", sample.CPUPercent, cfg.CPU.ScaleUp)
+	}
+	if cfg.Memory != nil && sample.MemoryPercent >= cfg.Memory.ScaleUp && current < cfg.MaxReplicas {
+		return current + 1, fmt.Sprintf("memory %.1f%% >= %.0f%%", sample.MemoryPercent, cfg.Memory.ScaleUp)
+	}
+
+	cpuLow := cfg.CPU == nil || sample.CPUPercent <= cfg.CPU.ScaleDown
+	memLow := cfg.Memory == nil || sample.MemoryPercent <= cfg.Memory.ScaleDown
+	if cpuLow && memLow && current > cfg.MinReplicas {
+		return current - 1, fmt.Sprintf("CPU %.1f%%, memory %.1f%% under scale-down thresholds", sample.CPUPercent, sample.MemoryPercent)
+	}
+	return current, ""
+}
+
+// sample averages CPU and memory usage over the replicas (sampled in parallel,
+// each Docker stats call takes about a second)
+func (s *Service) sample(ctx context.Context, names []string, spec orchestrator.Spec) (Sample, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		cpu     float64
+		mem     float64
+		sampled int
+	)
+	cores := spec.CPU
+	if cores <= 0 {
+		cores = 1
+	}
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			stats, err := s.dockerClient.Stats(ctx, name)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			cpu += stats.CPUPercent / cores
+			if stats.MemoryLimit > 0 {
+				mem += float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+			}
+			sampled++
+		}(name)
+	}
+	wg.Wait()
+
+	// Zero usage from failed samples would look like an idle app and scale it down
+	if sampled == 0 {
+		return Sample{}, fmt.Errorf("no stats available for %d replicas", len(names))
+	}
+	return Sample{
+		Replicas:      len(names),
+		CPUPercent:    cpu / float64(sampled),
+		MemoryPercent: mem / float64(sampled),
+	}, nil
+}
+
+// lastChange returns when the autoscaler last changed the replica count. Cooldown and
+// quota refusals changed nothing, so they don't restart the clock.
+func (s *Service) lastChange(projectID string) time.Time {
+	records, err := s.app.Dao().FindRecordsByFilter(
+		"autoscale_decisions", "project = {:project} && (decision = {:up} || decision = {:down})", "-created", 1, 0,
+		dbx.Params{"project": projectID, "up": DecisionScaleUp, "down": DecisionScaleDown},
+	)
+	if err != nil || len(records) == 0 {
+		return time.Time{}
+	}
+	return records[0].Created.Time()
+}
+
+func (s *Service) record(project *models.Record, decision string, target int, sample Sample, reason string, jobID string) {
+	collection, err := s.app.Dao().FindCollectionByNameOrId("autoscale_decisions")
+	if err != nil {
+		return
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("project", project.Id)
+	record.Set("decision", decision)
+	record.Set("replicas", sample.Replicas)
+	record.Set("target_replicas", target)
+	record.Set("cpu_percent", sample.CPUPercent)
+	record.Set("memory_percent", sample.MemoryPercent)
+	record.Set("reason", reason)
+	record.Set("job", jobID)
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to record autoscale decision: %v", err)
+	}
+}
+
+// Decisions returns a project's autoscale decisions, newest first
+func (s *Service) Decisions(projectID string, limit int) ([]*models.Record, error) {
+	return s.app.Dao().FindRecordsByFilter(
+		"autoscale_decisions", "project = {:project}", "-created", limit, 0,
+		dbx.Params{"project": projectID},
+	)
+}
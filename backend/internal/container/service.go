@@ -1,6 +1,7 @@
 package container
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os/exec"
@@ -10,7 +11,9 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/system"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -28,6 +31,9 @@ type Service interface {
 	PullImage(ctx context.Context, image string) error
 	BuildImage(ctx context.Context, contextPath string, tag string) error
 	ContainerExists(ctx context.Context, name string) (bool, error)
+	SystemInfo(ctx context.Context) (system.Info, error)
+	DiskUsage(ctx context.Context) (types.DiskUsage, error)
+	IsLocalDaemon() bool
 }
 
 type ContainerDetails struct {
@@ -220,11 +226,12 @@ func (s *service) GetContainerLogs(ctx context.Context, name string) (string, er
 	}
 	defer out.Close()
 
-	content, err := io.ReadAll(out)
-	if err != nil {
+	// Strip Docker's 8-byte multiplex headers (stdout and stderr interleaved)
+	var content bytes.Buffer
+	if _, err := stdcopy.StdCopy(&content, &content, out); err != nil {
 		return "", err
 	}
-	return string(content), nil
+	return content.String(), nil
 }
 
 func (s *service) PullImage(ctx context.Context, img string) error {
@@ -251,3 +258,19 @@ func (s *service) ContainerExists(ctx context.Context, name string) (bool, error
 	}
 	return false, err
 }
+
+func (s *service) SystemInfo(ctx context.Context) (system.Info, error) {
+	return s.cli.Info(ctx)
+}
+
+// DiskUsage is `docker system df`
+func (s *service) DiskUsage(ctx context.Context) (types.DiskUsage, error) {
+	return s.cli.DiskUsage(ctx, types.DiskUsageOptions{})
+}
+
+// IsLocalDaemon reports whether Docker is reached through a local socket, i.e. this
+// process sees the same /proc as the Docker host
+func (s *service) IsLocalDaemon() bool {
+	host := s.cli.DaemonHost()
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
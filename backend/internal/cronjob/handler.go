@@ -0,0 +1,160 @@
+package cronjob
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/cron"
+
+	"github.com/senvanda/backend/internal/history"
+)
+
+// Handler exposes the cron jobs of a project and their runs
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new cron job handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the cron job endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/cron", h.handleListJobs)
+	g.POST("/deploy/:id/cron/:job/run", h.handleRunJob)
+	g.GET("/deploy/:id/cron/runs", h.handleListRuns)
+	g.GET("/deploy/:id/cron/runs/:runId", h.handleGetRun)
+}
+
+type runView struct {
+	ID         string `json:"id"`
+	Job        string `json:"job"`
+	Command    string `json:"command"`
+	Trigger    string `json:"trigger"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int    `json:"duration_ms"`
+	Image      string `json:"image,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+}
+
+func toRunView(r *models.Record, withOutput bool) runView {
+	view := runView{
+		ID:         r.Id,
+		Job:        r.GetString("job"),
+		Command:    r.GetString("command"),
+		Trigger:    r.GetString("trigger"),
+		Status:     r.GetString("status"),
+		ExitCode:   r.GetInt("exit_code"),
+		DurationMS: r.GetInt("duration_ms"),
+		Image:      r.GetString("image"),
+		Error:      r.GetString("error"),
+		StartedAt:  r.GetString("started_at"),
+		FinishedAt: r.GetString("finished_at"),
+	}
+	if withOutput {
+		view.Output = r.GetString("output")
+	}
+	return view
+}
+
+type jobView struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"`
+	Command  string   `json:"command"`
+	Timeout  string   `json:"timeout"`
+	Error    string   `json:"error,omitempty"` // Invalid schedule: the job only runs manually
+	LastRun  *runView `json:"last_run,omitempty"`
+}
+
+// handleListJobs returns the configured jobs with their latest run
+// URL: GET /api/senvanda/deploy/:id/cron
+func (h *Handler) handleListJobs(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	jobs, err := h.service.Jobs(project)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	items := make([]jobView, 0, len(jobs))
+	for _, job := range jobs {
+		view := jobView{
+			Name:     job.Name,
+			Schedule: job.Schedule,
+			Command:  job.Command,
+			Timeout:  time.Duration(job.Timeout).String(),
+		}
+		if _, err := cron.NewSchedule(job.Schedule); err != nil {
+			view.Error = err.Error()
+		}
+		if runs, err := h.service.Runs(project.Id, job.Name, 1); err == nil && len(runs) > 0 {
+			last := toRunView(runs[0], false)
+			view.LastRun = &last
+		}
+		items = append(items, view)
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleRunJob starts a job now, outside of its schedule
+// URL: POST /api/senvanda/deploy/:id/cron/:job/run
+func (h *Handler) handleRunJob(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	run, err := h.service.Trigger(project, c.PathParam("job"), history.TriggerManual)
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return apis.NewNotFoundError(err.Error(), nil)
+	case errors.Is(err, ErrAlreadyRunning):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, toRunView(run, false))
+}
+
+// handleListRuns returns the run history, optionally filtered by job
+// URL: GET /api/senvanda/deploy/:id/cron/runs?job=cleanup&limit=50
+func (h *Handler) handleListRuns(c echo.Context) error {
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	records, err := h.service.Runs(c.PathParam("id"), c.QueryParam("job"), limit)
+	if err != nil {
+		return apis.NewNotFoundError("Failed to list cron runs", err)
+	}
+
+	items := make([]runView, 0, len(records))
+	for _, r := range records {
+		items = append(items, toRunView(r, false))
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleGetRun returns a single run including its captured output
+// URL: GET /api/senvanda/deploy/:id/cron/runs/:runId
+func (h *Handler) handleGetRun(c echo.Context) error {
+	run, err := h.service.app.Dao().FindRecordById("cron_runs", c.PathParam("runId"))
+	if err != nil || run.GetString("project") != c.PathParam("id") {
+		return apis.NewNotFoundError("Cron run not found", err)
+	}
+	return c.JSON(http.StatusOK, toRunView(run, true))
+}
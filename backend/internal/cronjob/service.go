@@ -0,0 +1,222 @@
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/cron"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// TriggerSchedule marks runs started by the scheduler (manual runs use history.TriggerManual)
+const TriggerSchedule = "schedule"
+
+// Run statuses stored in `cron_runs`
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusTimedOut  = "timed_out"
+)
+
+// ErrJobNotFound is returned when a project has no cron job with the given name
+var ErrJobNotFound = errors.New("cron job not found")
+
+// ErrAlreadyRunning is returned when the previous run of a job has not finished yet
+var ErrAlreadyRunning = errors.New("cron job is already running")
+
+// Service runs the `cronJobs` of every project on their schedule and records each run
+type Service struct {
+	app          core.App
+	orchestrator *orchestrator.Service
+	scheduler    *cron.Cron
+
+	mu      sync.Mutex
+	running map[string]bool // projectID/job -> run in progress
+}
+
+// NewService creates the cron job scheduler
+func NewService(app core.App, orchestratorSvc *orchestrator.Service) *Service {
+	return &Service{
+		app:          app,
+		orchestrator: orchestratorSvc,
+		scheduler:    cron.New(),
+		running:      make(map[string]bool),
+	}
+}
+
+// Start marks runs interrupted by a restart as failed and starts the minute ticker
+func (s *Service) Start() {
+	interrupted, err := s.app.Dao().FindRecordsByFilter(
+		"cron_runs", "status = {:status}", "", 0, 0,
+		dbx.Params{"status": StatusRunning},
+	)
+	if err == nil {
+		for _, run := range interrupted {
+			log.Printf("♻️ Cron run %s was interrupted by restart", run.Id)
+			s.finish(run, StatusFailed, -1, "", "interrupted by restart", 0)
+		}
+	}
+
+	s.scheduler.MustAdd("senvanda_cron_jobs", "* * * * *", s.tick)
+	s.scheduler.Start()
+}
+
+// tick starts every job due this minute
+func (s *Service) tick() {
+	moment := cron.NewMoment(time.Now())
+
+	projects, err := s.app.Dao().FindRecordsByFilter("projects", "status = 'online'", "", 0, 0)
+	if err != nil {
+		return
+	}
+
+	for _, project := range projects {
+		spec, err := orchestrator.SpecFromProject(project)
+		if err != nil {
+			continue
+		}
+		for _, job := range spec.CronJobs {
+			schedule, err := cron.NewSchedule(job.Schedule)
+			if err != nil || !schedule.IsDue(moment) {
+				continue
+			}
+			if _, err := s.Trigger(project, job.Name, TriggerSchedule); err != nil {
+				log.Printf("⚠️ Cron %s/%s: %v", spec.Name, job.Name, err)
+			}
+		}
+	}
+}
+
+// Jobs returns the configured cron jobs of a project
+func (s *Service) Jobs(project *models.Record) ([]orchestrator.CronJob, error) {
+	spec, err := orchestrator.SpecFromProject(project)
+	if err != nil {
+		return nil, err
+	}
+	return spec.CronJobs, nil
+}
+
+// Trigger starts a run of a job in the background and returns its record.
+// A job never overlaps with itself: a run due while the previous one is still going is skipped.
+func (s *Service) Trigger(project *models.Record, name string, trigger string) (*models.Record, error) {
+	jobs, err := s.Jobs(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var job *orchestrator.CronJob
+	for i := range jobs {
+		if jobs[i].Name == name {
+			job = &jobs[i]
+			break
+		}
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	key := project.Id + "/" + job.Name
+	s.mu.Lock()
+	if s.running[key] {
+		s.mu.Unlock()
+		return nil, ErrAlreadyRunning
+	}
+	s.running[key] = true
+	s.mu.Unlock()
+
+	run, err := s.start(project, *job, trigger)
+	if err != nil {
+		s.release(key)
+		return nil, err
+	}
+
+	go func() {
+		defer s.release(key)
+		s.execute(project, *job, run)
+	}()
+	return run, nil
+}
+
+func (s *Service) release(key string) {
+	s.mu.Lock()
+	delete(s.running, key)
+	s.mu.Unlock()
+}
+
+func (s *Service) start(project *models.Record, job orchestrator.CronJob, trigger string) (*models.Record, error) {
+	collection, err := s.app.Dao().FindCollectionByNameOrId("cron_runs")
+	if err != nil {
+		return nil, err
+	}
+
+	run := models.NewRecord(collection)
+	run.Set("project", project.Id)
+	run.Set("job", job.Name)
+	run.Set("schedule", job.Schedule)
+	run.Set("command", job.Command)
+	run.Set("trigger", trigger)
+	run.Set("status", StatusRunning)
+	run.Set("started_at", types.NowDateTime())
+	if err := s.app.Dao().SaveRecord(run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// execute runs the job container and stores its outcome on the run record
+func (s *Service) execute(project *models.Record, job orchestrator.CronJob, run *models.Record) {
+	log.Printf("⏰ Cron %s/%s started (%s)", project.GetString("name"), job.Name, run.GetString("trigger"))
+	started := time.Now()
+
+	result, image, err := s.orchestrator.RunCronJob(context.Background(), project, job)
+	duration := time.Since(started)
+	run.Set("image", image)
+
+	switch {
+	case err != nil:
+		log.Printf("❌ Cron %s/%s failed: %v", project.GetString("name"), job.Name, err)
+		s.finish(run, StatusFailed, -1, result.Output, err.Error(), duration)
+	case result.TimedOut:
+		log.Printf("⌛ Cron %s/%s timed out after %s", project.GetString("name"), job.Name, time.Duration(job.Timeout))
+		s.finish(run, StatusTimedOut, result.ExitCode, result.Output, fmt.Sprintf("timed out after %s", time.Duration(job.Timeout)), duration)
+	case result.ExitCode != 0:
+		log.Printf("❌ Cron %s/%s exited with code %d", project.GetString("name"), job.Name, result.ExitCode)
+		s.finish(run, StatusFailed, result.ExitCode, result.Output, fmt.Sprintf("exited with code %d", result.ExitCode), duration)
+	default:
+		log.Printf("✅ Cron %s/%s finished in %s", project.GetString("name"), job.Name, duration.Round(time.Millisecond))
+		s.finish(run, StatusSucceeded, 0, result.Output, "", duration)
+	}
+}
+
+func (s *Service) finish(run *models.Record, status string, exitCode int, output string, reason string, duration time.Duration) {
+	run.Set("status", status)
+	run.Set("exit_code", exitCode)
+	run.Set("output", output)
+	run.Set("error", reason)
+	run.Set("duration_ms", duration.Milliseconds())
+	run.Set("finished_at", types.NowDateTime())
+	if err := s.app.Dao().SaveRecord(run); err != nil {
+		log.Printf("⚠️ Failed to save cron run %s: %v", run.Id, err)
+	}
+}
+
+// Runs returns the runs of a project (optionally of one job), newest first
+func (s *Service) Runs(projectID string, job string, limit int) ([]*models.Record, error) {
+	filter := "project = {:project}"
+	if job != "" {
+		filter += " && job = {:job}"
+	}
+	return s.app.Dao().FindRecordsByFilter(
+		"cron_runs", filter, "-created", limit, 0,
+		dbx.Params{"project": projectID, "job": job},
+	)
+}
@@ -0,0 +1,146 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/secrets"
+)
+
+// envKeyPattern is what Docker and shells accept as a variable name
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// UpdateEnvReq sets and removes project environment variables in one change.
+// Secret marks the variables in Set as secrets; a variable that already is a secret
+// stays one when set again.
+type UpdateEnvReq struct {
+	Set    map[string]string `json:"set"`
+	Unset  []string          `json:"unset"`
+	Secret bool              `json:"secret"`
+}
+
+// GetEnv returns the environment variables stored in the project settings, secrets masked
+func (s *service) GetEnv(ctx context.Context, projectID string) ([]EnvVar, error) {
+	record, err := s.app.Dao().FindRecordById("projects", projectID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := readSettings(record)
+	if err != nil {
+		return nil, err
+	}
+	envs, err := envFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	return maskEnv(envs), nil
+}
+
+// UpdateEnv applies req to the project settings. Existing keys keep their position,
+// new keys are appended. Takes effect on the next deploy.
+func (s *service) UpdateEnv(ctx context.Context, projectID string, req UpdateEnvReq) ([]EnvVar, error) {
+	for key := range req.Set {
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid environment variable name %q", key)
+		}
+	}
+
+	record, err := s.app.Dao().FindRecordById("projects", projectID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := readSettings(record)
+	if err != nil {
+		return nil, err
+	}
+	envs, err := envFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	envs = slices.DeleteFunc(envs, func(e EnvVar) bool { return slices.Contains(req.Unset, e.Key) })
+	for _, key := range slices.Sorted(maps.Keys(req.Set)) {
+		value := req.Set[key]
+		i := slices.IndexFunc(envs, func(e EnvVar) bool { return e.Key == key })
+		if i >= 0 {
+			envs[i].Value = value
+			envs[i].Secret = envs[i].Secret || req.Secret
+		} else {
+			envs = append(envs, EnvVar{Key: key, Value: value, Secret: req.Secret})
+		}
+	}
+
+	// The secrets hook encrypts new secret values when the record is saved
+	settings["envVars"] = envs
+	record.Set("settings", settings)
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		return nil, err
+	}
+	return maskEnv(envs), nil
+}
+
+// maskEnv hides secret values, and values still encrypted from older settings
+func maskEnv(envs []EnvVar) []EnvVar {
+	for i, e := range envs {
+		if e.Secret || secrets.IsEncrypted(e.Value) {
+			envs[i].Value = secrets.Mask
+			envs[i].Secret = true
+		}
+	}
+	return envs
+}
+
+// readSettings decodes the project settings, keeping fields this package does not know
+func readSettings(record *models.Record) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	if raw := record.GetString("settings"); raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return nil, fmt.Errorf("invalid project settings: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+func envFromSettings(settings map[string]interface{}) ([]EnvVar, error) {
+	envs := []EnvVar{}
+	if settings["envVars"] == nil {
+		return envs, nil
+	}
+	raw, err := json.Marshal(settings["envVars"])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &envs); err != nil {
+		return nil, fmt.Errorf("invalid project env vars: %w", err)
+	}
+	if envs == nil {
+		envs = []EnvVar{}
+	}
+	return envs, nil
+}
+
+// UnmarshalJSON accepts numbers and booleans as values ({"key": "PORT", "value": 3000}),
+// formatted by orchestrator.EnvValue
+func (e *EnvVar) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key    string      `json:"key"`
+		Value  interface{} `json:"value"`
+		Secret bool        `json:"secret"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	value, err := orchestrator.EnvValue(raw.Value)
+	if err != nil {
+		return fmt.Errorf("env var %s: %w", raw.Key, err)
+	}
+	*e = EnvVar{Key: raw.Key, Value: value, Secret: raw.Secret}
+	return nil
+}
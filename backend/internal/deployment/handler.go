@@ -1,12 +1,24 @@
 package deployment
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase/apis"
-	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/queue"
+	"github.com/senvanda/backend/internal/quota"
+	"github.com/senvanda/backend/internal/secrets"
+	"github.com/senvanda/backend/internal/token"
 )
 
 // Handler handles HTTP requests for deployment operations
@@ -19,39 +31,50 @@ func NewHandler(s Service) *Handler {
 	return &Handler{service: s}
 }
 
-// RegisterRoutes registers the deployment routes to the Echo group
+// RegisterPublicRoutes registers the routes that authenticate with their own webhook token
+func (h *Handler) RegisterPublicRoutes(g *echo.Group) {
+	g.POST("/webhook/redeploy", h.handleWebhookRedeploy)
+}
+
+// RegisterRoutes registers the dashboard routes. The group must require auth and
+// project ownership; host-wide operations are limited to admins here.
 func (h *Handler) RegisterRoutes(g *echo.Group) {
+	adminOnly := auth.RequireAdmin()
+	g.GET("/deploy/info", h.handleGetInfo, adminOnly)
 	g.GET("/deploy/projects", h.handleListProjects)
-	g.GET("/deploy/legacy", h.handleListLegacy)
+	g.GET("/deploy/legacy", h.handleListLegacy, adminOnly)
 	g.POST("/deploy/create", h.handleDeployProject)
 	g.POST("/deploy/draft", h.handleCreateDraft)
 	g.POST("/deploy/scan", h.handleScan)
-	g.POST("/deploy/prune", h.handlePruneProjects)
-	g.POST("/deploy/adopt", h.handleAdoptProject)
+	g.POST("/deploy/prune", h.handlePruneProjects, adminOnly)
+	g.POST("/deploy/adopt", h.handleAdoptProject, adminOnly)
 	g.GET("/deploy/:id/logs", h.handleGetLogs)
 	g.POST("/deploy/:id/action", h.handleProjectAction)
-	g.POST("/webhook/redeploy", h.handleWebhookRedeploy)
+	g.GET("/deploy/:id/env", h.handleGetEnv)
+	g.PATCH("/deploy/:id/env", h.handleUpdateEnv)
 }
 
+// handleGetInfo returns the Docker host overview and capacity committed to projects
+// URL: GET /api/senvanda/deploy/info
 func (h *Handler) handleGetInfo(c echo.Context) error {
 	info, err := h.service.GetDockerInfo(c.Request().Context())
 	if err != nil {
 		return apis.NewBadRequestError("Failed to get docker info. Ensure Docker daemon is running.", err)
 	}
 
-	return c.JSON(200, map[string]interface{}{
-		"message":        "Docker Connected Successfully",
-		"server_version": info.ServerVersion,
-		"containers":     info.Containers,
-		"running":        info.ContainersRunning,
-	})
+	return c.JSON(200, info)
 }
 
 func (h *Handler) handleListProjects(c echo.Context) error {
-	projects, err := h.service.GetProjectsWithStatus(c.Request().Context())
+	// Admins see every project, users their own and their teams'
+	projects, err := h.service.GetProjectsWithStatus(c.Request().Context(), auth.UserID(c))
 	if err != nil {
 		return apis.NewBadRequestError("Failed to list projects", err)
 	}
+	// A project-restricted API token only lists its own projects
+	projects = slices.DeleteFunc(projects, func(p ProjectStatus) bool {
+		return !token.AllowsProject(c, p.ID)
+	})
 	return c.JSON(200, projects)
 }
 
@@ -62,7 +85,21 @@ func (h *Handler) handleProjectAction(c echo.Context) error {
 		return apis.NewBadRequestError("Invalid request", err)
 	}
 
-	if err := h.service.ActionProject(c.Request().Context(), id, strings.ToLower(data.Action)); err != nil {
+	action := strings.ToLower(data.Action)
+	audit.SetAction(c, "project."+action)
+	if action == "redeploy" {
+		job, err := h.service.QueueRedeploy(c.Request().Context(), id, history.TriggerManual)
+		if quota.IsExceeded(err) {
+			return apis.NewForbiddenError(err.Error(), err)
+		}
+		if err != nil {
+			return apis.NewBadRequestError("Failed to queue redeploy", err)
+		}
+		audit.Queued(c, job.Id)
+		return c.JSON(202, queue.JobResponse(job, "Redeploy queued"))
+	}
+
+	if err := h.service.ActionProject(c.Request().Context(), id, action); err != nil {
 		return apis.NewBadRequestError(fmt.Sprintf("Failed to %s project", data.Action), err)
 	}
 
@@ -78,32 +115,39 @@ func (h *Handler) processProjectCreation(c echo.Context, isDraft bool) error {
 
 	// Force isDraft based on endpoint
 	data.IsDraft = isDraft
+	audit.SetAction(c, "project.create")
+	audit.Param(c, "name", data.Name)
+	audit.Param(c, "draft", data.IsDraft)
+	if data.Team != "" {
+		audit.Param(c, "team", data.Team)
+	}
 
-	// 1. Resolve User/Owner
-	authRecord, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
-	if authRecord == nil {
-		// If not a regular user, check if Admin
-		admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
-		if admin != nil {
-			// If Admin, try to find the first user to assign ownership to (fallback)
-			user, err := h.service.FindFirstUser(c.Request().Context())
-			if err != nil {
-				return apis.NewBadRequestError("Admin authorized, but no Users found to assign project to. Create a user first.", err)
-			}
-			authRecord = user
-			fmt.Println("[DEBUG] Using fallback user for Admin action:", authRecord.Id)
-		} else {
-			return apis.NewForbiddenError("Unauthorized", nil)
+	// 1. Resolve User/Owner: users own what they create, admins may name an owner
+	owner := auth.User(c)
+	if auth.IsAdmin(c) && data.User != "" {
+		user, err := h.service.FindUser(c.Request().Context(), data.User)
+		if err != nil {
+			return apis.NewBadRequestError("Owner not found", err)
 		}
+		owner = user
 	}
 
-	fmt.Printf("[DEBUG] Creating project '%s' (Draft: %v) for user %s\n", data.Name, data.IsDraft, authRecord.Id)
+	ownerID := "admin"
+	if owner != nil {
+		ownerID = owner.Id
+	}
+	log.Printf("📦 Creating project %s (draft: %v) for %s", data.Name, data.IsDraft, ownerID)
 
-	project, err := h.service.CreateProject(c.Request().Context(), data, authRecord)
+	project, err := h.service.CreateProject(c.Request().Context(), data, owner)
+	if quota.IsExceeded(err) || errors.Is(err, ErrNotTeamDeveloper) {
+		return apis.NewForbiddenError(err.Error(), err)
+	}
 	if err != nil {
-		fmt.Printf("[ERROR] CreateProject failed: %v\n", err)
+		log.Printf("❌ Failed to create project %s: %v", data.Name, err)
 		return apis.NewBadRequestError("Failed to create project: "+err.Error(), err)
 	}
+	audit.SetProject(c, project.Id)
+	secrets.MaskRecord(project)
 
 	return c.JSON(200, project)
 }
@@ -156,40 +200,79 @@ func (h *Handler) handleGetLogs(c echo.Context) error {
 	return c.JSON(200, map[string]string{"logs": logs})
 }
 
+// handleGetEnv returns the project environment variables
+// URL: GET /api/senvanda/deploy/:id/env
+func (h *Handler) handleGetEnv(c echo.Context) error {
+	envs, err := h.service.GetEnv(c.Request().Context(), c.PathParam("id"))
+	if err != nil {
+		return apis.NewBadRequestError("Failed to read environment variables", err)
+	}
+	return c.JSON(200, envs)
+}
+
+// handleUpdateEnv sets and removes environment variables, applied on the next deploy
+// URL: PATCH /api/senvanda/deploy/:id/env {"set": {"KEY": "value"}, "unset": ["OLD_KEY"]}
+func (h *Handler) handleUpdateEnv(c echo.Context) error {
+	audit.SetAction(c, "project.env")
+	var data UpdateEnvReq
+	if err := c.Bind(&data); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+	// Keys only, values may be secrets
+	audit.Param(c, "set", slices.Sorted(maps.Keys(data.Set)))
+	audit.Param(c, "unset", data.Unset)
+	audit.Param(c, "secret", data.Secret)
+
+	envs, err := h.service.UpdateEnv(c.Request().Context(), c.PathParam("id"), data)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to update environment variables: "+err.Error(), err)
+	}
+	return c.JSON(200, envs)
+}
+
+// handlePruneProjects removes ghost projects; dry_run only lists them
+// URL: POST /api/senvanda/deploy/prune?dry_run=true
 func (h *Handler) handlePruneProjects(c echo.Context) error {
-	count, err := h.service.PruneMissingProjects(c.Request().Context())
+	audit.SetAction(c, "project.prune")
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+	pruned, err := h.service.PruneMissingProjects(c.Request().Context(), dryRun)
 	if err != nil {
 		return apis.NewBadRequestError("Failed to prune projects", err)
 	}
+	audit.Param(c, "pruned_count", len(pruned))
 	return c.JSON(200, map[string]interface{}{
 		"status":       "ok",
-		"pruned_count": count,
+		"dry_run":      dryRun,
+		"pruned_count": len(pruned),
+		"pruned":       pruned,
 	})
 }
 
 type AdoptReq struct {
 	ContainerID string `json:"containerID"`
+	User        string `json:"user"` // Owner to assign, empty = admin-managed
 }
 
 func (h *Handler) handleAdoptProject(c echo.Context) error {
+	audit.SetAction(c, "project.adopt")
 	var data AdoptReq
 	if err := c.Bind(&data); err != nil {
 		return apis.NewBadRequestError("Invalid request", err)
 	}
+	audit.Param(c, "container_id", data.ContainerID)
+	audit.Param(c, "user", data.User)
 
-	authRecord, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record)
-	if authRecord == nil {
-		user, err := h.service.FindFirstUser(c.Request().Context())
-		if err != nil {
-			return err
+	if data.User != "" {
+		if _, err := h.service.FindUser(c.Request().Context(), data.User); err != nil {
+			return apis.NewBadRequestError("Owner not found", err)
 		}
-		authRecord = user
 	}
 
-	record, err := h.service.AdoptProject(c.Request().Context(), data.ContainerID, authRecord.Id)
+	record, err := h.service.AdoptProject(c.Request().Context(), data.ContainerID, data.User)
 	if err != nil {
 		return apis.NewBadRequestError("Adoption failed", err)
 	}
+	audit.SetProject(c, record.Id)
 
 	return c.JSON(200, record)
 }
@@ -208,12 +291,22 @@ func (h *Handler) handleWebhookRedeploy(c echo.Context) error {
 }
 
 func (h *Handler) processWebhookAction(c echo.Context, token string) error {
-	// Let's assume we added FindByToken to service or we do it here
-	// I'll add a trigger in service to handle this
-	err := h.service.ActionProjectByToken(c.Request().Context(), token, "redeploy")
+	audit.SetAction(c, "project.redeploy")
+	project, err := h.service.FindProjectByToken(c.Request().Context(), token)
+	if err != nil {
+		return apis.NewBadRequestError("Redeploy failed: token invalid or system error", err)
+	}
+	audit.SetActor(c, audit.ActorWebhook, project.Id, "webhook token")
+	audit.SetProject(c, project.Id)
+
+	job, err := h.service.QueueRedeploy(c.Request().Context(), project.Id, history.TriggerWebhook)
+	if quota.IsExceeded(err) {
+		return apis.NewForbiddenError(err.Error(), err)
+	}
 	if err != nil {
 		return apis.NewBadRequestError("Redeploy failed: token invalid or system error", err)
 	}
 
-	return c.JSON(200, map[string]string{"status": "success", "message": "Deployment triggered"})
+	audit.Queued(c, job.Id)
+	return c.JSON(200, map[string]string{"status": "success", "message": "Deployment triggered", "job_id": job.Id})
 }
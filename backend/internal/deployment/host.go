@@ -0,0 +1,142 @@
+package deployment
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// GetDockerInfo returns the host overview: versions, capacity, `docker system df`
+// and the share of CPU and memory already committed to projects
+func (s *service) GetDockerInfo(ctx context.Context) (*HostInfo, error) {
+	info, err := s.containers.SystemInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	host := &HostInfo{
+		ServerVersion:     info.ServerVersion,
+		OperatingSystem:   info.OperatingSystem,
+		KernelVersion:     info.KernelVersion,
+		Architecture:      info.Architecture,
+		Containers:        info.Containers,
+		ContainersRunning: info.ContainersRunning,
+		Images:            info.Images,
+		CPUs:              info.NCPU,
+		MemoryTotal:       info.MemTotal,
+	}
+
+	// Docker does not report free memory. With a local socket (the usual setup, even
+	// from inside a container) /proc/meminfo is the host's.
+	if s.containers.IsLocalDaemon() {
+		if available, ok := memAvailable(); ok {
+			host.MemoryAvailable = &available
+		}
+	}
+
+	// `docker system df` walks every layer and volume, so a slow disk only costs the summary
+	if df, err := s.containers.DiskUsage(ctx); err == nil {
+		host.Volumes = len(df.Volumes)
+		host.Disk.Images = df.LayersSize
+		for _, img := range df.Images {
+			if img.Containers == 0 {
+				host.Disk.ImagesReclaimable += img.Size - img.SharedSize
+			}
+		}
+		for _, c := range df.Containers {
+			host.Disk.Containers += c.SizeRw
+		}
+		for _, v := range df.Volumes {
+			if v.UsageData != nil && v.UsageData.Size > 0 {
+				host.Disk.Volumes += v.UsageData.Size
+			}
+		}
+		for _, bc := range df.BuildCache {
+			host.Disk.BuildCache += bc.Size
+			if !bc.InUse && !bc.Shared {
+				host.Disk.BuildCacheReclaimable += bc.Size
+			}
+		}
+		host.Disk.Total = host.Disk.Images + host.Disk.Containers + host.Disk.Volumes + host.Disk.BuildCache
+	}
+
+	committed, err := s.committedUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if host.CPUs > 0 {
+		committed.CPUPercent = committed.CPUs / float64(host.CPUs) * 100
+	}
+	if host.MemoryTotal > 0 {
+		committed.MemoryPercent = float64(committed.MemoryMB*1024*1024) / float64(host.MemoryTotal) * 100
+	}
+	host.Committed = committed
+	return host, nil
+}
+
+// committedUsage multiplies each project's resource limits by its running replicas.
+// Cron and release tasks are short-lived and not counted.
+func (s *service) committedUsage(ctx context.Context) (CommittedUsage, error) {
+	usage := CommittedUsage{Projects: []ProjectCommit{}}
+
+	containers, err := s.containers.ListContainers(ctx, false)
+	if err != nil {
+		return usage, err
+	}
+	running := make(map[string]int)
+	for _, c := range containers {
+		if projectID := c.Labels["senvanda.project_id"]; projectID != "" && !orchestrator.IsTask(c.Labels) {
+			running[projectID]++
+		}
+	}
+
+	for projectID, replicas := range running {
+		record, err := s.app.Dao().FindRecordById("projects", projectID)
+		if err != nil {
+			continue // Orphan container, not a project's commitment
+		}
+		spec, _ := orchestrator.SpecFromProject(record)
+		commit := ProjectCommit{
+			ID:       record.Id,
+			Name:     spec.Name,
+			Replicas: replicas,
+			CPUs:     spec.CPU * float64(replicas),
+			MemoryMB: spec.MemoryMB * int64(replicas),
+		}
+		usage.CPUs += commit.CPUs
+		usage.MemoryMB += commit.MemoryMB
+		usage.Projects = append(usage.Projects, commit)
+	}
+
+	sort.Slice(usage.Projects, func(i, j int) bool {
+		return usage.Projects[i].MemoryMB > usage.Projects[j].MemoryMB
+	})
+	return usage, nil
+}
+
+// memAvailable reads MemAvailable from /proc/meminfo in bytes
+func memAvailable() (int64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return kb * 1024, true
+		}
+	}
+	return 0, false
+}
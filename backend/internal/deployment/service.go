@@ -4,22 +4,23 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
-	"os"
-	"path/filepath"
-
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/system"
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/models"
 
+	"github.com/senvanda/backend/internal/auth"
 	"github.com/senvanda/backend/internal/cicd"
 	"github.com/senvanda/backend/internal/container"
 	"github.com/senvanda/backend/internal/git"
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/queue"
+	"github.com/senvanda/backend/internal/quota"
 )
 
 type service struct {
@@ -27,38 +28,55 @@ type service struct {
 	containers container.Service
 	git        git.Service
 	cicd       cicd.Service
+	jobs       *queue.Service
+	quotas     *quota.Service
 }
 
-func NewService(app core.App, containerSvc container.Service, gitSvc git.Service, cicdSvc cicd.Service) Service {
+func NewService(app core.App, containerSvc container.Service, gitSvc git.Service, cicdSvc cicd.Service, jobs *queue.Service, quotas *quota.Service) Service {
 	return &service{
 		app:        app,
 		containers: containerSvc,
 		git:        gitSvc,
 		cicd:       cicdSvc,
+		jobs:       jobs,
+		quotas:     quotas,
 	}
 }
 
-func (s *service) GetDockerInfo(ctx context.Context) (system.Info, error) {
-	// For info, we might still need a direct call or add to container interface
-	// I'll keep it simple for now and assume container service can provide it if needed
-	// But system.Info is very specific.
-	return system.Info{}, nil // Placeholder or extend container service
-}
-
-func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, error) {
+// GetProjectsWithStatus lists the projects of userID and of their teams with their
+// container state. An empty userID (admins) lists every project and also records
+// discovered containers.
+func (s *service) GetProjectsWithStatus(ctx context.Context, userID string) ([]ProjectStatus, error) {
 	// 1. Fetch current Managed Projects from DB
-	records, err := s.app.Dao().FindRecordsByFilter("projects", "id != ''", "-created", 200, 0, nil)
+	filter, params := "id != ''", dbx.Params{}
+	teamRoles := make(map[string]string) // team ID -> caller's role
+	if userID != "" {
+		filter, params = "user = {:user}", dbx.Params{"user": userID}
+		for i, teamID := range auth.TeamIDs(s.app, userID) {
+			key := fmt.Sprintf("team%d", i)
+			filter += " || team = {:" + key + "}"
+			params[key] = teamID
+			teamRoles[teamID] = auth.TeamRole(s.app, teamID, userID)
+		}
+	}
+	records, err := s.app.Dao().FindRecordsByFilter("projects", filter, "-created", 200, 0, params)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. Identify Orphans (Containers with 'senvanda-' prefix but no DB record)
-	containers, _ := s.containers.ListContainers(ctx, true)
+	// Only for admins: discovered containers belong to nobody until adopted
+	var containers []types.Container
+	if userID == "" {
+		containers, _ = s.containers.ListContainers(ctx, true)
+	}
 	dbMap := make(map[string]bool)
 	for _, r := range records {
 		dbMap[r.GetString("containerId")] = true
-		dbMap["senvanda-"+r.GetString("name")] = true
+		dbMap[orchestrator.ContainerName(r.GetString("name"))] = true
+		dbMap[orchestrator.LegacyContainerName(r.GetString("name"))] = true
 		dbMap[r.GetString("name")] = true
+		dbMap[r.Id] = true
 	}
 
 	for _, c := range containers {
@@ -82,6 +100,10 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 		if dbMap[fullName] || dbMap[c.ID] {
 			continue
 		}
+		// Replicas & candidates carry the ID of the project that owns them
+		if owner := c.Labels["senvanda.project_id"]; owner != "" && dbMap[owner] {
+			continue
+		}
 
 		// Tentukan Kategori
 		category := "discovered"
@@ -97,15 +119,11 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 
 		fmt.Printf("[DISCOVERY] Found new container: %s. Category: %s\n", fullName, category)
 
-		// Get default system user
-		user, _ := s.FindFirstUser(ctx)
-
-		// Build Record
+		// Build Record (no owner: only admins see it until it is adopted)
 		collection, _ := s.app.Dao().FindCollectionByNameOrId("projects")
 		rec := models.NewRecord(collection)
 
 		rec.Set("name", cleanName)
-		rec.Set("user", user.Id)
 		rec.Set("status", "running")
 		rec.Set("containerId", c.ID)
 		rec.Set("image", c.Image)
@@ -128,7 +146,7 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 	}
 
 	// 3. Prepare Final Status List
-	var results []ProjectStatus
+	results := []ProjectStatus{}
 	for _, r := range records {
 		name := r.GetString("name")
 		cid := r.GetString("containerId")
@@ -145,8 +163,14 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 			if cid != "" {
 				cJSON, err = s.containers.InspectContainer(ctx, cid)
 			} else {
-				// Try Prefixed then Direct
-				cJSON, err = s.containers.InspectContainer(ctx, "senvanda-"+name)
+				// Try Replica, Pipeline, Legacy Prefixed then Direct
+				cJSON, err = s.containers.InspectContainer(ctx, orchestrator.ReplicaName(name, 1))
+				if err != nil {
+					cJSON, err = s.containers.InspectContainer(ctx, orchestrator.ContainerName(name))
+				}
+				if err != nil {
+					cJSON, err = s.containers.InspectContainer(ctx, orchestrator.LegacyContainerName(name))
+				}
 				if err != nil {
 					cJSON, err = s.containers.InspectContainer(ctx, name)
 				}
@@ -163,6 +187,11 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 			}
 		}
 
+		role := auth.RoleOwner // Admins and personal owners
+		if userID != "" && r.GetString("user") != userID {
+			role = teamRoles[r.GetString("team")]
+		}
+
 		results = append(results, ProjectStatus{
 			ID:       r.Id,
 			Name:     name,
@@ -173,6 +202,8 @@ func (s *service) GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, e
 			Created:  r.Created,
 			Image:    r.GetString("image"),
 			RepoUrl:  r.GetString("repoUrl"),
+			Team:     r.GetString("team"),
+			Role:     role,
 		})
 	}
 
@@ -185,147 +216,54 @@ func (s *service) ActionProject(ctx context.Context, projectID string, action st
 		return err
 	}
 
-	containerName := "senvanda-" + record.GetString("name")
-
+	var act func(ctx context.Context, name string) error
 	switch action {
 	case "start":
-		return s.containers.StartContainer(ctx, containerName)
+		act = s.containers.StartContainer
 	case "stop":
-		return s.containers.StopContainer(ctx, containerName)
+		act = s.containers.StopContainer
 	case "restart":
-		return s.containers.RestartContainer(ctx, containerName)
+		act = s.containers.RestartContainer
 	case "redeploy":
-		// Cleanup
-		_ = s.containers.RemoveContainer(ctx, containerName)
-
-		// Prepare Config
-		port := record.GetInt("port")
-		name := record.GetString("name")
-		image := record.GetString("image")
-		repoUrl := record.GetString("repoUrl")
-
-		if image == "custom-build" && repoUrl != "" {
-			// DEVOPS: Build from Source
-			tempPath := filepath.Join(os.TempDir(), "senvanda-build-"+name)
-			_ = os.RemoveAll(tempPath)
-
-			// Clone (We can repurpose git service or use exec)
-			cmd := exec.Command("git", "clone", "--depth", "1", repoUrl, tempPath)
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to clone for build: %v", err)
-			}
-			defer os.RemoveAll(tempPath)
-
-			// Build
-			tag := "senvanda/project-" + name + ":latest"
-			if err := s.containers.BuildImage(ctx, tempPath, tag); err != nil {
-				return fmt.Errorf("build failed: %v", err)
-			}
-			image = tag
-		}
-
-		if image == "" || image == "custom-build" {
-			image = "nginx:alpine"
-		}
-
-		var envs []string
-		var cpu, memory string
-		settings := record.Get("settings")
-
-		if data, ok := settings.(map[string]interface{}); ok {
-			if envList, ok := data["envVars"].([]interface{}); ok {
-				for _, e := range envList {
-					if kv, ok := e.(map[string]interface{}); ok {
-						key := fmt.Sprintf("%v", kv["key"])
-						val := fmt.Sprintf("%v", kv["value"])
-						if key != "" {
-							envs = append(envs, fmt.Sprintf("%s=%s", key, val))
-						}
-					}
-				}
-			}
-			if res, ok := data["resources"].(map[string]interface{}); ok {
-				cpu = fmt.Sprintf("%v", res["cpu"])
-				memory = fmt.Sprintf("%v", res["memory"])
-			}
-		}
-
-		// Domain Logic
-		domain := fmt.Sprintf("%s.senvanda.local", name)
-		if data, ok := settings.(map[string]interface{}); ok {
-			if d, ok := data["domain"].(string); ok && d != "" {
-				domain = d
-			}
-		}
-
-		// Extract Volumes from DB
-		var binds []string
-		if volumesData := record.Get("volumes"); volumesData != nil {
-			if volList, ok := volumesData.([]interface{}); ok {
-				for _, v := range volList {
-					if vm, ok := v.(map[string]interface{}); ok {
-						host := fmt.Sprintf("%v", vm["host"])
-						container := fmt.Sprintf("%v", vm["container"])
-						if host != "" && container != "" {
-							binds = append(binds, fmt.Sprintf("%s:%s", host, container))
-						}
-					} else if vs, ok := v.(string); ok {
-						binds = append(binds, vs)
-					}
-				}
-			}
-		}
-
-		// Port Logic: Ensure we have a valid port
-		if port == 0 {
-			port = 80 // Default internal port
-		}
-
-		containerCfg := &container.Config{
-			Name:    containerName,
-			Image:   image,
-			Env:     envs,
-			Volumes: binds,
-			Ports:   map[string]string{fmt.Sprintf("%d/tcp", port): strconv.Itoa(port)},
-			Labels: map[string]string{
-				"senvanda.project":    name,
-				"senvanda.redeployed": time.Now().Format(time.RFC3339),
-				"caddy":               domain,
-				"caddy.reverse_proxy": fmt.Sprintf("{{upstreams %d}}", port),
-			},
-		}
-		containerCfg.Resources.CPU = cpu
-		containerCfg.Resources.Memory = memory
-
-		id, err := s.containers.CreateContainer(ctx, containerCfg)
-
-		if err != nil {
-			record.Set("status", "failed")
-			s.app.Dao().SaveRecord(record)
-			return err
-		}
+		_, err := s.QueueRedeploy(ctx, record.Id, history.TriggerManual)
+		return err
+	default:
+		return fmt.Errorf("unknown action: %s", action)
+	}
 
-		if err := s.containers.StartContainer(ctx, containerName); err != nil {
-			record.Set("status", "failed")
-			s.app.Dao().SaveRecord(record)
+	// Apply to every replica of the project
+	for _, name := range s.resolveReplicas(ctx, record) {
+		if err := act(ctx, name); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		record.Set("containerId", id)
-		record.Set("status", "running")
-		s.app.Dao().SaveRecord(record)
-		return nil
-	default:
-		return fmt.Errorf("unknown action: %s", action)
+// QueueRedeploy puts a redeploy of the project on the deploy queue and returns the job.
+// The orchestrator pipeline executes it, same as CI and rollback deploys.
+// A redeploy the owner's quota no longer allows is refused here, before queueing.
+func (s *service) QueueRedeploy(ctx context.Context, projectID string, trigger string) (*models.Record, error) {
+	record, err := s.app.Dao().FindRecordById("projects", projectID)
+	if err != nil {
+		return nil, err
 	}
+	spec, err := orchestrator.SpecFromProject(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quotas.Admit(record, spec); err != nil {
+		return nil, err
+	}
+	return s.jobs.Enqueue(projectID, queue.KindRedeploy, trigger, nil)
 }
 
-func (s *service) ActionProjectByToken(ctx context.Context, token string, action string) error {
+func (s *service) FindProjectByToken(ctx context.Context, token string) (*models.Record, error) {
 	record, err := s.app.Dao().FindFirstRecordByData("projects", "webhookToken", token)
 	if err != nil {
-		return fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
 	}
-	return s.ActionProject(ctx, record.Id, action)
+	return record, nil
 }
 
 func (s *service) CreateProject(ctx context.Context, req CreateProjectReq, user *models.Record) (*models.Record, error) {
@@ -335,12 +273,26 @@ func (s *service) CreateProject(ctx context.Context, req CreateProjectReq, user
 		return nil, fmt.Errorf("invalid project name: '%s'. please provide a descriptive name.", req.Name)
 	}
 
-	// 1b. Port allocation
+	// 1b. Port: the port the app listens on inside senvanda-apps (no host binding)
 	port := req.Port
 	if port == 0 {
-		var err error
-		port, err = s.findAvailablePort()
-		if err != nil {
+		port = 80
+	}
+
+	// 1c. Owner: nil for admin-managed projects, which are not subject to quotas
+	ownerID := ""
+	if user != nil {
+		ownerID = user.Id
+	}
+
+	// 1d. Team: the owner must be allowed to deploy in it
+	if req.Team != "" && ownerID != "" && !auth.HasRole(auth.TeamRole(s.app, req.Team, ownerID), auth.RoleDeveloper) {
+		return nil, ErrNotTeamDeveloper
+	}
+
+	// 1e. Quota: a draft only takes a project slot, a deploy also its resources
+	if ownerID != "" {
+		if err := s.quotas.CheckNewProject(ownerID); err != nil {
 			return nil, err
 		}
 	}
@@ -354,7 +306,8 @@ func (s *service) CreateProject(ctx context.Context, req CreateProjectReq, user
 	record := models.NewRecord(collection)
 	record.Set("name", req.Name)
 	record.Set("port", port)
-	record.Set("user", user.Id)
+	record.Set("user", ownerID)
+	record.Set("team", req.Team)
 	record.Set("repoUrl", req.RepoUrl)
 	record.Set("framework", req.Framework)
 	record.Set("image", req.Image)
@@ -366,6 +319,16 @@ func (s *service) CreateProject(ctx context.Context, req CreateProjectReq, user
 		record.Set("status", "building")
 	}
 
+	if !req.IsDraft {
+		spec, err := orchestrator.SpecFromProject(record)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.quotas.Admit(record, spec); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.app.Dao().SaveRecord(record); err != nil {
 		return nil, err
 	}
@@ -374,9 +337,8 @@ func (s *service) CreateProject(ctx context.Context, req CreateProjectReq, user
 		return record, nil
 	}
 
-	// 3. Deployment
-	err = s.ActionProject(ctx, record.Id, "redeploy")
-	if err != nil {
+	// 3. Deployment (queued, the dashboard follows progress via the record)
+	if _, err := s.QueueRedeploy(ctx, record.Id, history.TriggerManual); err != nil {
 		return nil, err
 	}
 
@@ -425,8 +387,10 @@ func (s *service) DiscoverLegacy(ctx context.Context) ([]LegacyApp, error) {
 	managedMap := make(map[string]bool)
 	for _, r := range records {
 		managedMap[r.GetString("containerId")] = true
-		managedMap["senvanda-"+r.GetString("name")] = true
+		managedMap[orchestrator.ContainerName(r.GetString("name"))] = true
+		managedMap[orchestrator.LegacyContainerName(r.GetString("name"))] = true
 		managedMap[r.GetString("name")] = true
+		managedMap[r.Id] = true
 	}
 
 	var apps []LegacyApp
@@ -443,6 +407,9 @@ func (s *service) DiscoverLegacy(ctx context.Context) ([]LegacyApp, error) {
 
 		// IS IT MANAGED? Check ID and Names
 		isManaged := managedMap[c.ID] || managedMap[name] || managedMap[strings.TrimPrefix(name, "senvanda-")]
+		if owner := c.Labels["senvanda.project_id"]; owner != "" && managedMap[owner] {
+			isManaged = true
+		}
 
 		if !isManaged {
 			var ports []int
@@ -469,8 +436,7 @@ func (s *service) GetProjectLogs(ctx context.Context, projectID string) (string,
 		return "", err
 	}
 
-	containerName := "senvanda-" + record.GetString("name")
-	return s.containers.GetContainerLogs(ctx, containerName)
+	return s.containers.GetContainerLogs(ctx, s.resolveContainer(ctx, record))
 }
 
 func (s *service) AdoptProject(ctx context.Context, containerID string, userID string) (*models.Record, error) {
@@ -500,23 +466,65 @@ func (s *service) AdoptProject(ctx context.Context, containerID string, userID s
 	return record, nil
 }
 
-func (s *service) PruneMissingProjects(ctx context.Context) (int, error) {
+// PruneMissingProjects deletes anonymous projects, drafts older than a day and projects
+// without any container left. With dryRun it only reports what would be deleted.
+func (s *service) PruneMissingProjects(ctx context.Context, dryRun bool) ([]PrunedProject, error) {
 	records, err := s.app.Dao().FindRecordsByFilter("projects", "id != ''", "", 2000, 0, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	// Existence is decided from one listing: a container labelled with the project ID
+	// (any replica, whatever containerId says after an interrupted rollout), the recorded
+	// containerId, or an unlabelled container with one of the project's legacy names
+	containers, listErr := s.containers.ListContainers(ctx, true)
+	if listErr != nil {
+		fmt.Printf("[PRUNE] Docker connection error, keeping every deployed project: %v\n", listErr)
+	}
+	labelled := make(map[string]bool)
+	ids := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, c := range containers {
+		if projectID := c.Labels["senvanda.project_id"]; projectID != "" {
+			labelled[projectID] = true
+			continue
+		}
+		ids[c.ID] = true
+		for _, n := range c.Names {
+			names[strings.TrimPrefix(n, "/")] = true
+		}
+	}
+	exists := func(r *models.Record, name string) bool {
+		if labelled[r.Id] {
+			return true
+		}
+		if cid := r.GetString("containerId"); cid != "" {
+			for id := range ids {
+				if strings.HasPrefix(id, cid) {
+					return true
+				}
+			}
+		}
+		return names[name] || names[orchestrator.LegacyContainerName(name)] || names[orchestrator.ContainerName(name)]
+	}
+
+	pruned := []PrunedProject{}
+	prune := func(r *models.Record, reason string) {
+		if !dryRun {
+			if err := s.app.Dao().DeleteRecord(r); err != nil {
+				return
+			}
+		}
+		pruned = append(pruned, PrunedProject{ID: r.Id, Name: r.GetString("name"), Reason: reason})
 	}
 
-	count := 0
 	for _, r := range records {
 		name := strings.TrimSpace(r.GetString("name"))
-		cid := r.GetString("containerId")
 
 		// 1. ANONYMOUS PRUNING (Aggressive)
 		if name == "" || strings.ToLower(name) == "untitled" || strings.ToLower(name) == "untitled project" {
-			fmt.Printf("[PRUNE] Removing anonymous zombie: %s\n", r.Id)
-			if err := s.app.Dao().DeleteRecord(r); err == nil {
-				count++
-			}
+			fmt.Printf("[PRUNE] Removing anonymous zombie: %s (dry run: %v)\n", r.Id, dryRun)
+			prune(r, "anonymous")
 			continue
 		}
 
@@ -524,8 +532,7 @@ func (s *service) PruneMissingProjects(ctx context.Context) (int, error) {
 		if r.GetString("status") == "draft" {
 			createdAt := r.GetDateTime("created").Time()
 			if time.Since(createdAt) > 24*time.Hour {
-				_ = s.app.Dao().DeleteRecord(r)
-				count++
+				prune(r, "stale draft")
 			}
 			continue
 		}
@@ -535,35 +542,55 @@ func (s *service) PruneMissingProjects(ctx context.Context) (int, error) {
 			continue
 		}
 
-		var exists bool
-		var err error
+		if listErr == nil && !exists(r, name) {
+			fmt.Printf("[PRUNE] DELETING GHOST: %s (ID: %s / CID: %s, dry run: %v)\n", name, r.Id, r.GetString("containerId"), dryRun)
+			prune(r, "container missing")
+		}
+	}
+	return pruned, nil
+}
 
-		if cid != "" {
-			exists, err = s.containers.ContainerExists(ctx, cid)
-		} else {
-			// Legacy Fallback
-			prefixed := name
-			if !strings.HasPrefix(name, "senvanda-") {
-				prefixed = "senvanda-" + name
-			}
-			e1, _ := s.containers.ContainerExists(ctx, prefixed)
-			e2, _ := s.containers.ContainerExists(ctx, name)
-			exists = e1 || e2
+// resolveContainer returns the container backing a project: the recorded containerId,
+// the legacy senvanda-<name> for projects not redeployed since the engines were unified,
+// the pre-replica name (senvanda-app-<name>), or replica 1 (senvanda-app-<name>-1).
+func (s *service) resolveContainer(ctx context.Context, record *models.Record) string {
+	if cid := record.GetString("containerId"); cid != "" {
+		if ok, _ := s.containers.ContainerExists(ctx, cid); ok {
+			return cid
+		}
+	}
+	name := record.GetString("name")
+	if ok, _ := s.containers.ContainerExists(ctx, orchestrator.LegacyContainerName(name)); ok {
+		if exists, _ := s.containers.ContainerExists(ctx, orchestrator.ContainerName(name)); !exists {
+			return orchestrator.LegacyContainerName(name)
 		}
+	}
+	if ok, _ := s.containers.ContainerExists(ctx, orchestrator.ContainerName(name)); ok {
+		return orchestrator.ContainerName(name)
+	}
+	return orchestrator.ReplicaName(name, 1)
+}
 
-		if err != nil {
-			fmt.Printf("[PRUNE] Docker connection error for %s: %v\n", name, err)
+// resolveReplicas returns every container of a project (labelled by the orchestrator),
+// falling back to resolveContainer for projects deployed before replicas existed
+func (s *service) resolveReplicas(ctx context.Context, record *models.Record) []string {
+	containers, _ := s.containers.ListContainers(ctx, true)
+
+	var names []string
+	for _, c := range containers {
+		if c.Labels["senvanda.project_id"] != record.Id || len(c.Names) == 0 {
 			continue
 		}
-
-		if !exists {
-			fmt.Printf("[PRUNE] DELETING GHOST: %s (ID: %s / CID: %s)\n", name, r.Id, cid)
-			if err := s.app.Dao().DeleteRecord(r); err == nil {
-				count++
-			}
+		name := strings.TrimPrefix(c.Names[0], "/")
+		if strings.HasSuffix(name, "-next") || orchestrator.IsTask(c.Labels) {
+			continue // Candidate owned by a running deploy, or a one-off task
 		}
+		names = append(names, name)
 	}
-	return count, nil
+	if len(names) == 0 {
+		names = append(names, s.resolveContainer(ctx, record))
+	}
+	return names
 }
 
 func (s *service) findAvailablePort() (int, error) {
@@ -587,18 +614,9 @@ func (s *service) findAvailablePort() (int, error) {
 	return 0, fmt.Errorf("no port available")
 }
 
-func (s *service) FindFirstUser(ctx context.Context) (*models.Record, error) {
-	records, err := s.app.Dao().FindRecordsByFilter("users", "id != ''", "-created", 1, 0, nil)
-	if err == nil && len(records) > 0 {
-		return records[0], nil
-	}
-
-	collection, _ := s.app.Dao().FindCollectionByNameOrId("users")
-	record := models.NewRecord(collection)
-	record.Set("email", "system@senvanda.local")
-	record.SetPassword("1234567890")
-	s.app.Dao().SaveRecord(record)
-	return record, nil
+// FindUser returns a users record, to assign as project owner
+func (s *service) FindUser(ctx context.Context, id string) (*models.Record, error) {
+	return s.app.Dao().FindRecordById("users", id)
 }
 
 func extractNameFromUrl(url string) string {
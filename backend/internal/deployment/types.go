@@ -2,26 +2,31 @@ package deployment
 
 import (
 	"context"
+	"errors"
 
-	"github.com/docker/docker/api/types/system"
 	"github.com/pocketbase/pocketbase/models"
 )
 
 // Service defines the interface for the high-level orchestrator
 type Service interface {
-	GetDockerInfo(ctx context.Context) (system.Info, error)
+	GetDockerInfo(ctx context.Context) (*HostInfo, error)
 	CreateProject(ctx context.Context, req CreateProjectReq, user *models.Record) (*models.Record, error)
-	GetProjectsWithStatus(ctx context.Context) ([]ProjectStatus, error)
+	GetProjectsWithStatus(ctx context.Context, userID string) ([]ProjectStatus, error)
 	ActionProject(ctx context.Context, projectID string, action string) error
-	ActionProjectByToken(ctx context.Context, token string, action string) error
+	FindProjectByToken(ctx context.Context, token string) (*models.Record, error)
+	QueueRedeploy(ctx context.Context, projectID string, trigger string) (*models.Record, error)
 	ScanGitRepository(ctx context.Context, repoUrl string) (*ScanResult, error)
-	FindFirstUser(ctx context.Context) (*models.Record, error)
+	FindUser(ctx context.Context, id string) (*models.Record, error)
 	GetProjectLogs(ctx context.Context, projectID string) (string, error) // NEW
 
 	// NEW: Management & Adoption
 	DiscoverLegacy(ctx context.Context) ([]LegacyApp, error)
 	AdoptProject(ctx context.Context, containerID string, userID string) (*models.Record, error)
-	PruneMissingProjects(ctx context.Context) (int, error)
+	PruneMissingProjects(ctx context.Context, dryRun bool) ([]PrunedProject, error)
+
+	// Environment variables in the project settings
+	GetEnv(ctx context.Context, projectID string) ([]EnvVar, error)
+	UpdateEnv(ctx context.Context, projectID string, req UpdateEnvReq) ([]EnvVar, error)
 }
 
 type ProjectStatus struct {
@@ -35,6 +40,56 @@ type ProjectStatus struct {
 	Image    string                 `json:"image"`
 	RepoUrl  string                 `json:"repoUrl"`
 	Labels   map[string]interface{} `json:"labels,omitempty"`
+	Team     string                 `json:"team,omitempty"`
+	Role     string                 `json:"role"` // Caller's role: owner, developer or viewer
+}
+
+// HostInfo is the Docker host overview and how much of it projects have reserved
+type HostInfo struct {
+	ServerVersion   string `json:"server_version"`
+	OperatingSystem string `json:"operating_system"`
+	KernelVersion   string `json:"kernel_version"`
+	Architecture    string `json:"architecture"`
+
+	Containers        int `json:"containers"`
+	ContainersRunning int `json:"containers_running"`
+	Images            int `json:"images"`
+	Volumes           int `json:"volumes"`
+
+	CPUs            int    `json:"cpus"`
+	MemoryTotal     int64  `json:"memory_total"`               // Bytes
+	MemoryAvailable *int64 `json:"memory_available,omitempty"` // Bytes, only known for a local daemon
+
+	Disk      DiskUsage      `json:"disk"`
+	Committed CommittedUsage `json:"committed"`
+}
+
+// DiskUsage summarizes `docker system df` (bytes)
+type DiskUsage struct {
+	Images                int64 `json:"images"`
+	ImagesReclaimable     int64 `json:"images_reclaimable"` // Images no container uses
+	Containers            int64 `json:"containers"`         // Writable layers
+	Volumes               int64 `json:"volumes"`
+	BuildCache            int64 `json:"build_cache"`
+	BuildCacheReclaimable int64 `json:"build_cache_reclaimable"`
+	Total                 int64 `json:"total"`
+}
+
+// CommittedUsage is the CPU and memory reserved by the limits of running project containers
+type CommittedUsage struct {
+	CPUs          float64         `json:"cpus"`
+	CPUPercent    float64         `json:"cpu_percent"` // Of the host's CPUs
+	MemoryMB      int64           `json:"memory_mb"`
+	MemoryPercent float64         `json:"memory_percent"` // Of the host's memory
+	Projects      []ProjectCommit `json:"projects"`
+}
+
+type ProjectCommit struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Replicas int     `json:"replicas"` // Running containers
+	CPUs     float64 `json:"cpus"`
+	MemoryMB int64   `json:"memory_mb"`
 }
 
 type LegacyApp struct {
@@ -45,6 +100,13 @@ type LegacyApp struct {
 	Port  []int  `json:"ports"`
 }
 
+// PrunedProject is a project removed (or, in a dry run, selected) by PruneMissingProjects
+type PrunedProject struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"` // anonymous, stale draft, container missing
+}
+
 type ScanResult struct {
 	Framework     string            `json:"framework"`
 	Version       string            `json:"version"`
@@ -65,6 +127,87 @@ type ProjectSettings struct {
 	EnvVars      []EnvVar  `json:"envVars"`
 	Domain       string    `json:"domain"`
 	Resources    Resources `json:"resources"`
+
+	// Readiness probe run by the orchestrator before routing traffic
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// Weighted traffic schedule for new releases (e.g. 10% -> 50% -> 100%)
+	Canary *Canary `json:"canary,omitempty"`
+
+	// Horizontal scaling: number of containers and how Caddy spreads requests over them
+	Replicas      int    `json:"replicas,omitempty"`
+	LoadBalancing string `json:"loadBalancing,omitempty"` // round_robin, least_conn, random, ip_hash
+
+	// CPU/memory-driven replica count between minReplicas and maxReplicas
+	Autoscale *Autoscale `json:"autoscale,omitempty"`
+
+	// Per-branch preview environments at <name>-<branch-slug>.senvanda.local
+	Previews *Previews `json:"previews,omitempty"`
+
+	// Scheduled commands run in one-off containers from the current image
+	CronJobs []CronJob `json:"cronJobs,omitempty"`
+
+	// Run in a throwaway container from the new image before the traffic switch (e.g. migrations)
+	ReleaseCommand string `json:"releaseCommand,omitempty"`
+
+	// How long collected container logs are kept (maxAge, maxLines)
+	LogRetention *LogRetention `json:"logRetention,omitempty"`
+}
+
+// The blocks below only describe the shape of the settings JSON; defaults and
+// validation are applied by the orchestrator when it builds the deploy spec.
+// Durations are a Go duration string ("5s") or a number of seconds.
+
+type HealthCheck struct {
+	Path           string      `json:"path"`
+	ExpectedStatus int         `json:"expectedStatus,omitempty"`
+	Interval       interface{} `json:"interval,omitempty"`
+	Timeout        interface{} `json:"timeout,omitempty"`
+	Retries        int         `json:"retries,omitempty"`
+	StartPeriod    interface{} `json:"startPeriod,omitempty"`
+}
+
+type Canary struct {
+	Enabled bool         `json:"enabled"`
+	Steps   []CanaryStep `json:"steps"`
+}
+
+type CanaryStep struct {
+	Weight int         `json:"weight"`
+	Pause  interface{} `json:"pause,omitempty"` // Missing or 0 = wait for a manual promote
+}
+
+type Autoscale struct {
+	Enabled           bool        `json:"enabled"`
+	MinReplicas       int         `json:"minReplicas,omitempty"`
+	MaxReplicas       int         `json:"maxReplicas,omitempty"`
+	CPU               *Thresholds `json:"cpu,omitempty"`
+	Memory            *Thresholds `json:"memory,omitempty"`
+	ScaleUpCooldown   interface{} `json:"scaleUpCooldown,omitempty"`
+	ScaleDownCooldown interface{} `json:"scaleDownCooldown,omitempty"`
+}
+
+type Thresholds struct {
+	ScaleUp   float64 `json:"scaleUp"`
+	ScaleDown float64 `json:"scaleDown"`
+}
+
+type Previews struct {
+	Enabled  bool        `json:"enabled"`
+	Branches []string    `json:"branches,omitempty"`
+	TTL      interface{} `json:"ttl,omitempty"`
+}
+
+type CronJob struct {
+	Name     string      `json:"name"`
+	Schedule string      `json:"schedule"`
+	Command  string      `json:"command"`
+	Timeout  interface{} `json:"timeout,omitempty"`
+}
+
+type LogRetention struct {
+	MaxAge   interface{} `json:"maxAge,omitempty"`
+	MaxLines int         `json:"maxLines,omitempty"`
 }
 
 type Resources struct {
@@ -73,10 +216,14 @@ type Resources struct {
 }
 
 type EnvVar struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"` // Encrypted at rest, masked in responses
 }
 
+// ErrNotTeamDeveloper is returned when a project is created for a team the owner cannot deploy in
+var ErrNotTeamDeveloper = errors.New("only team owners and developers can create projects for the team")
+
 type CreateProjectReq struct {
 	Name      string          `json:"name"`
 	RepoUrl   string          `json:"repoUrl"`
@@ -85,6 +232,8 @@ type CreateProjectReq struct {
 	Port      int             `json:"port"`
 	IsDraft   bool            `json:"isDraft"`
 	Settings  ProjectSettings `json:"settings"`
+	User      string          `json:"user"` // Owner, admins only (users always own what they create)
+	Team      string          `json:"team"` // Team that shares the project (caller must be owner or developer)
 }
 
 type ActionProjectReq struct {
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// Handler streams deploy events to the dashboard
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new deploy event handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the event stream endpoint
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/events", h.handleStream)
+}
+
+// handleStream replays the events of a deployment (?deployment=, default: the latest one)
+// and then follows the project live until the client disconnects.
+func (h *Handler) handleStream(c echo.Context) error {
+	projectID := c.PathParam("id")
+	if _, err := h.service.app.Dao().FindRecordById("projects", projectID); err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	deploymentID := c.QueryParam("deployment")
+	if deploymentID != "" {
+		// Access was checked for :id only, so the deployment must belong to it
+		deployment, err := h.service.app.Dao().FindRecordById("deployments", deploymentID)
+		if err != nil || deployment.GetString("project") != projectID {
+			return apis.NewNotFoundError("Deployment not found", err)
+		}
+	} else {
+		latest, err := h.service.app.Dao().FindRecordsByFilter(
+			"deployments", "project = {:project}", "-started_at", 1, 0,
+			dbx.Params{"project": projectID},
+		)
+		if err == nil && len(latest) > 0 {
+			deploymentID = latest[0].Id
+		}
+	}
+
+	// Subscribe before replaying so nothing emitted in between is lost
+	live, unsubscribe := h.service.Subscribe(projectID)
+	defer unsubscribe()
+
+	stream := NewStream(c)
+	seen := make(map[string]bool)
+
+	if deploymentID != "" {
+		past, _ := h.service.History(deploymentID)
+		for _, ev := range past {
+			seen[ev.ID] = true
+			if err := stream.Send(ev.Type, ev.ID, ev); err != nil {
+				return nil
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return nil
+			}
+		case ev := <-live:
+			if seen[ev.ID] {
+				continue
+			}
+			if err := stream.Send(ev.Type, ev.ID, ev); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Event types emitted by the deploy pipeline
+const (
+	TypePhaseStarted   = "phase_started"
+	TypePhaseFinished  = "phase_finished"
+	TypePullProgress   = "pull_progress"
+	TypeBuildLog       = "build_log"
+	TypeHealthProbe    = "health_probe"
+	TypeRouteSwitched  = "route_switched"
+	TypeCanaryStep     = "canary_step"
+	TypeCanaryPromoted = "canary_promoted"
+	TypeCanaryAborted  = "canary_aborted"
+	TypeReplicaReady   = "replica_ready"
+	TypeScaled         = "scaled"
+	TypeFailed         = "deploy_failed"
+	TypeSucceeded      = "deploy_succeeded"
+)
+
+// Event is one typed step of a deployment's progress
+type Event struct {
+	ID         string                 `json:"id"`
+	Project    string                 `json:"project"`
+	Deployment string                 `json:"deployment"`
+	Seq        int                    `json:"seq"`
+	Type       string                 `json:"type"`
+	Phase      string                 `json:"phase,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Time       time.Time              `json:"time"`
+}
+
+// Service persists deploy events in `deploy_events` and fans them out to live subscribers
+type Service struct {
+	app core.App
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{} // projectID -> subscriber channels
+}
+
+// NewService creates a new deploy event service
+func NewService(app core.App) *Service {
+	return &Service{
+		app:         app,
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Publish stores the event and delivers it to everyone following the project.
+// Slow subscribers miss live events rather than blocking the deploy.
+func (s *Service) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	if collection, err := s.app.Dao().FindCollectionByNameOrId("deploy_events"); err == nil {
+		record := models.NewRecord(collection)
+		record.Set("project", ev.Project)
+		record.Set("deployment", ev.Deployment)
+		record.Set("seq", ev.Seq)
+		record.Set("type", ev.Type)
+		record.Set("phase", ev.Phase)
+		record.Set("message", ev.Message)
+		record.Set("data", ev.Data)
+		if err := s.app.Dao().SaveRecord(record); err != nil {
+			log.Printf("⚠️ Failed to persist deploy event: %v", err)
+		} else {
+			ev.ID = record.Id
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers[ev.Project] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe follows live events of a project. Call the returned func to unsubscribe.
+func (s *Service) Subscribe(projectID string) (<-chan Event, func()) {
+	ch := make(chan Event, 256)
+
+	s.mu.Lock()
+	if s.subscribers[projectID] == nil {
+		s.subscribers[projectID] = make(map[chan Event]struct{})
+	}
+	s.subscribers[projectID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers[projectID], ch)
+		if len(s.subscribers[projectID]) == 0 {
+			delete(s.subscribers, projectID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// History returns the stored events of a deployment in emission order
+func (s *Service) History(deploymentID string) ([]Event, error) {
+	records, err := s.app.Dao().FindRecordsByFilter(
+		"deploy_events", "deployment = {:deployment}", "seq", 0, 0,
+		dbx.Params{"deployment": deploymentID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Event, 0, len(records))
+	for _, r := range records {
+		ev := Event{
+			ID:         r.Id,
+			Project:    r.GetString("project"),
+			Deployment: r.GetString("deployment"),
+			Seq:        r.GetInt("seq"),
+			Type:       r.GetString("type"),
+			Phase:      r.GetString("phase"),
+			Message:    r.GetString("message"),
+			Time:       r.Created.Time(),
+		}
+		_ = r.UnmarshalJSONField("data", &ev.Data)
+		items = append(items, ev)
+	}
+	return items, nil
+}
+
+// Emitter publishes the events of a single deployment with an increasing sequence number
+type Emitter struct {
+	service    *Service
+	project    string
+	deployment string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// For returns an emitter bound to one deployment. A nil Service yields a no-op emitter.
+func (s *Service) For(projectID string, deploymentID string) *Emitter {
+	return &Emitter{service: s, project: projectID, deployment: deploymentID}
+}
+
+// Emit publishes one event
+func (e *Emitter) Emit(eventType string, phase string, message string, data map[string]interface{}) {
+	if e == nil || e.service == nil {
+		return
+	}
+	e.mu.Lock()
+	e.seq++
+	seq := e.seq
+	e.mu.Unlock()
+
+	e.service.Publish(Event{
+		Project:    e.project,
+		Deployment: e.deployment,
+		Seq:        seq,
+		Type:       eventType,
+		Phase:      phase,
+		Message:    message,
+		Data:       data,
+	})
+}
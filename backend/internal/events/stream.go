@@ -0,0 +1,53 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+// Stream writes Server-Sent Events to an echo response
+type Stream struct {
+	c echo.Context
+}
+
+// NewStream sends the SSE headers and returns a writer for the response
+func NewStream(c echo.Context) *Stream {
+	h := c.Response().Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // Don't let a proxy buffer the stream
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Flush()
+	return &Stream{c: c}
+}
+
+// Send writes one SSE message with a JSON payload
+func (s *Stream) Send(event string, id string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(s.c.Response(), "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.c.Response(), "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.c.Response().Flush()
+	return nil
+}
+
+// Ping writes an SSE comment to keep idle connections open
+func (s *Stream) Ping() error {
+	if _, err := fmt.Fprint(s.c.Response(), ": ping\n\n"); err != nil {
+		return err
+	}
+	s.c.Response().Flush()
+	return nil
+}
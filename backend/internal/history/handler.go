@@ -0,0 +1,76 @@
+package history
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+// Handler exposes the deployment timeline to the dashboard
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new deployment history handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the history endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/deployments", h.handleListDeployments)
+}
+
+type deploymentView struct {
+	ID          string `json:"id"`
+	Trigger     string `json:"trigger"`
+	Image       string `json:"image"`
+	ImageDigest string `json:"image_digest"`
+	CommitSHA   string `json:"commit_sha"`
+	BuildNumber int    `json:"build_number"`
+	StartedAt   string `json:"started_at"`
+	FinishedAt  string `json:"finished_at"`
+	DurationMs  int64  `json:"duration_ms"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	RollbackOf  string `json:"rollback_of,omitempty"`
+
+	ReleaseOutput   string `json:"release_output,omitempty"`
+	ReleaseExitCode int    `json:"release_exit_code,omitempty"`
+}
+
+func (h *Handler) handleListDeployments(c echo.Context) error {
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	records, err := h.service.List(c.PathParam("id"), limit)
+	if err != nil {
+		return apis.NewNotFoundError("Failed to list deployments", err)
+	}
+
+	items := make([]deploymentView, 0, len(records))
+	for _, r := range records {
+		items = append(items, deploymentView{
+			ID:          r.Id,
+			Trigger:     r.GetString("trigger"),
+			Image:       r.GetString("image"),
+			ImageDigest: r.GetString("image_digest"),
+			CommitSHA:   r.GetString("commit_sha"),
+			BuildNumber: r.GetInt("build_number"),
+			StartedAt:   r.GetString("started_at"),
+			FinishedAt:  r.GetString("finished_at"),
+			DurationMs:  Duration(r).Milliseconds(),
+			Status:      r.GetString("status"),
+			Error:       r.GetString("error"),
+			RollbackOf:  r.GetString("rollback_of"),
+
+			ReleaseOutput:   r.GetString("release_output"),
+			ReleaseExitCode: r.GetInt("release_exit_code"),
+		})
+	}
+
+	return c.JSON(200, items)
+}
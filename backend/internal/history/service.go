@@ -0,0 +1,278 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Trigger describes what started a deployment
+const (
+	TriggerWebhook   = "webhook"   // Token-based redeploy hook
+	TriggerManual    = "manual"    // Dashboard action / project creation
+	TriggerCI        = "ci"        // Woodpecker /deploy-final callback
+	TriggerRollback  = "rollback"  // Redeploy of an earlier successful release
+	TriggerAutoscale = "autoscale" // Replica count changed by the autoscaler
+)
+
+// Deployment status values
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Meta carries the information known when a deployment starts
+type Meta struct {
+	Trigger     string
+	Image       string
+	CommitSHA   string
+	BuildNumber int
+	RollbackOf  string // ID of the deployment being restored (rollbacks only)
+	JobID       string // deploy_jobs record that ran this deployment
+}
+
+// Snapshot is the project configuration captured when a deployment starts
+type Snapshot struct {
+	Settings types.JsonRaw `json:"settings"`
+	Volumes  types.JsonRaw `json:"volumes"`
+	Port     int           `json:"port"`
+}
+
+// Service records one `deployments` entry per deploy attempt
+type Service struct {
+	app core.App
+}
+
+// NewService creates a new deployment history service
+func NewService(app core.App) *Service {
+	return &Service{app: app}
+}
+
+// Start creates a "running" deployment record for the project
+func (s *Service) Start(project *models.Record, meta Meta) (*models.Record, error) {
+	collection, err := s.app.Dao().FindCollectionByNameOrId("deployments")
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("project", project.Id)
+	record.Set("trigger", meta.Trigger)
+	record.Set("image", meta.Image)
+	record.Set("commit_sha", meta.CommitSHA)
+	record.Set("build_number", meta.BuildNumber)
+	record.Set("rollback_of", meta.RollbackOf)
+	record.Set("job", meta.JobID)
+	record.Set("snapshot", Snapshot{
+		Settings: jsonRaw(project.Get("settings")),
+		Volumes:  jsonRaw(project.Get("volumes")),
+		Port:     project.GetInt("port"),
+	})
+	record.Set("started_at", types.NowDateTime())
+	record.Set("status", StatusRunning)
+
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to record deployment for %s: %v", project.GetString("name"), err)
+		return nil, err
+	}
+	return record, nil
+}
+
+// SetImage updates the image reference (and digest, once known) of a running deployment
+func (s *Service) SetImage(deployment *models.Record, image string, digest string) {
+	if deployment == nil {
+		return
+	}
+	if image != "" {
+		deployment.Set("image", image)
+	}
+	if digest != "" {
+		deployment.Set("image_digest", digest)
+	}
+	s.save(deployment)
+}
+
+// SetReleaseOutput attaches the output and exit code of the release command
+func (s *Service) SetReleaseOutput(deployment *models.Record, output string, exitCode int) {
+	if deployment == nil {
+		return
+	}
+	deployment.Set("release_output", output)
+	deployment.Set("release_exit_code", exitCode)
+	s.save(deployment)
+}
+
+// Finish closes a deployment with its final status. deployErr nil means success.
+func (s *Service) Finish(deployment *models.Record, deployErr error) {
+	if deployment == nil {
+		return
+	}
+	deployment.Set("finished_at", types.NowDateTime())
+	if deployErr != nil {
+		deployment.Set("status", StatusFailed)
+		deployment.Set("error", deployErr.Error())
+	} else {
+		deployment.Set("status", StatusSuccess)
+	}
+	s.save(deployment)
+}
+
+// List returns the deployments of a project, newest first
+func (s *Service) List(projectID string, limit int) ([]*models.Record, error) {
+	if _, err := s.app.Dao().FindRecordById("projects", projectID); err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	return s.app.Dao().FindRecordsByFilter(
+		"deployments",
+		"project = {:project}",
+		"-started_at",
+		limit,
+		0,
+		dbx.Params{"project": projectID},
+	)
+}
+
+// FindRollbackTarget resolves the deployment a rollback should restore.
+// With an explicit deploymentID it must be a successful deployment of the project;
+// otherwise the newest successful deployment before the live release is used.
+func (s *Service) FindRollbackTarget(projectID string, deploymentID string) (*models.Record, error) {
+	if deploymentID != "" {
+		target, err := s.app.Dao().FindRecordById("deployments", deploymentID)
+		if err != nil || target.GetString("project") != projectID {
+			return nil, fmt.Errorf("deployment %s not found for this project", deploymentID)
+		}
+		if target.GetString("status") != StatusSuccess {
+			return nil, fmt.Errorf("deployment %s did not succeed and cannot be restored", deploymentID)
+		}
+		return target, nil
+	}
+
+	latest, err := s.app.Dao().FindRecordsByFilter(
+		"deployments",
+		"project = {:project} && status = {:status}",
+		"-started_at",
+		1,
+		0,
+		dbx.Params{"project": projectID, "status": StatusSuccess},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no previous successful deployment to roll back to")
+	}
+	// latest[0] is live; a rollback runs the release it restored, not a new one
+	release := s.liveRelease(latest[0])
+
+	candidates, err := s.app.Dao().FindRecordsByFilter(
+		"deployments",
+		"project = {:project} && status = {:status} && started_at < {:before} && id != {:release}",
+		"-started_at",
+		1,
+		0,
+		dbx.Params{
+			"project": projectID,
+			"status":  StatusSuccess,
+			"before":  release.GetString("started_at"),
+			"release": release.Id,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no previous successful deployment to roll back to")
+	}
+	return candidates[0], nil
+}
+
+// liveRelease follows the rollback_of chain of the live deployment back to the
+// release it actually runs, so repeated rollbacks keep stepping back in time
+func (s *Service) liveRelease(live *models.Record) *models.Record {
+	release := live
+	seen := map[string]bool{live.Id: true}
+	for {
+		restoredID := release.GetString("rollback_of")
+		if restoredID == "" || seen[restoredID] {
+			return release
+		}
+		restored, err := s.app.Dao().FindRecordById("deployments", restoredID)
+		if err != nil || restored.GetString("project") != live.GetString("project") {
+			return release
+		}
+		seen[restoredID] = true
+		release = restored
+	}
+}
+
+// RestoreSnapshot copies the configuration captured by a deployment back onto the project
+func RestoreSnapshot(project *models.Record, deployment *models.Record) {
+	var snap Snapshot
+	if err := deployment.UnmarshalJSONField("snapshot", &snap); err != nil {
+		return
+	}
+	if len(snap.Settings) > 0 {
+		project.Set("settings", snap.Settings)
+	}
+	if len(snap.Volumes) > 0 {
+		project.Set("volumes", snap.Volumes)
+	}
+	if snap.Port > 0 {
+		project.Set("port", snap.Port)
+	}
+}
+
+// PinnedImage returns the immutable reference (repo@sha256:...) of a deployment's image
+func PinnedImage(deployment *models.Record) string {
+	image := deployment.GetString("image")
+	digest := deployment.GetString("image_digest")
+	if digest == "" || strings.Contains(image, "@") {
+		return image
+	}
+
+	// Strip the tag, but keep a registry port ("host:5000/owner/repo:tag")
+	repo := image
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo + "@" + digest
+}
+
+func jsonRaw(value any) types.JsonRaw {
+	switch v := value.(type) {
+	case types.JsonRaw:
+		return v
+	case nil:
+		return nil
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return raw
+	}
+}
+
+func (s *Service) save(deployment *models.Record) {
+	if err := s.app.Dao().SaveRecord(deployment); err != nil {
+		log.Printf("⚠️ Failed to update deployment %s: %v", deployment.Id, err)
+	}
+}
+
+// Duration returns how long a finished deployment took
+func Duration(deployment *models.Record) time.Duration {
+	start := deployment.GetDateTime("started_at").Time()
+	end := deployment.GetDateTime("finished_at").Time()
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -25,20 +25,57 @@ func NewClient(baseURL string) *Client {
 	}
 }
 
-// AddLinkDomain menambahkan domain baru yang mengarah ke target internal (IP:Port)
-// Payload Caddy ini sedikit kompleks karena strukturnya nested.
-// Kita inject route ini ke dalam http server pertama (index 0).
-func (c *Client) AddLinkDomain(domain string, target string) error {
-	// Construct Caddy Route JSON Payload structure
-	// Ini merepresentasikan satu blok routing:
-	// "match host" -> "handle reverse proxy"
+// routeID returns the Caddy "@id" used for a domain route
+func routeID(domain string) string {
+	return fmt.Sprintf("route-%s", domain)
+}
 
-	// Target format: "172.18.0.x:8080"
+// Upstream is one backend of a domain route
+type Upstream struct {
+	Dial   string `json:"dial"`             // "172.18.0.x:8080"
+	Weight int    `json:"weight,omitempty"` // Only used by weighted_round_robin
+}
 
-	routeID := fmt.Sprintf("route-%s", domain)
+// Load balancing policies supported by the route builder
+const (
+	PolicyWeighted   = "weighted_round_robin"
+	PolicyRoundRobin = "round_robin"
+	PolicyLeastConn  = "least_conn"
+	PolicyRandom     = "random"
+	PolicyIPHash     = "ip_hash"
+)
 
-	payload := map[string]interface{}{
-		"@id": routeID, // ID unik agar bisa diedit/hapus nanti
+// buildRoute menyusun payload route Caddy untuk satu domain.
+// Ini merepresentasikan satu blok routing:
+// "match host" -> "handle reverse proxy"
+// Dengan lebih dari satu upstream, policy menentukan pembagian traffic.
+func buildRoute(domain string, upstreams []Upstream, policy string) map[string]interface{} {
+	dials := make([]map[string]interface{}, 0, len(upstreams))
+	for _, u := range upstreams {
+		dials = append(dials, map[string]interface{}{"dial": u.Dial})
+	}
+
+	proxy := map[string]interface{}{
+		"handler":   "reverse_proxy",
+		"upstreams": dials,
+	}
+
+	if len(upstreams) > 1 && policy != "" {
+		selection := map[string]interface{}{"policy": policy}
+		if policy == PolicyWeighted {
+			weights := make([]int, 0, len(upstreams))
+			for _, u := range upstreams {
+				weights = append(weights, u.Weight)
+			}
+			selection["weights"] = weights
+		}
+		proxy["load_balancing"] = map[string]interface{}{
+			"selection_policy": selection,
+		}
+	}
+
+	return map[string]interface{}{
+		"@id": routeID(domain), // ID unik agar bisa diedit/hapus nanti
 		"match": []map[string]interface{}{
 			{
 				"host": []string{domain},
@@ -49,21 +86,23 @@ func (c *Client) AddLinkDomain(domain string, target string) error {
 				"handler": "subroute",
 				"routes": []map[string]interface{}{
 					{
-						"handle": []map[string]interface{}{
-							{
-								"handler": "reverse_proxy",
-								"upstreams": []map[string]interface{}{
-									{"dial": target},
-								},
-							},
-						},
+						"handle": []map[string]interface{}{proxy},
 					},
 				},
 			},
 		},
 	}
+}
 
-	jsonData, err := json.Marshal(payload)
+// AddLinkDomain menambahkan domain baru yang mengarah ke target internal (IP:Port)
+// Payload Caddy ini sedikit kompleks karena strukturnya nested.
+// Kita inject route ini ke dalam http server pertama (index 0).
+func (c *Client) AddLinkDomain(domain string, target string) error {
+	return c.addRoute(buildRoute(domain, []Upstream{{Dial: target}}, ""))
+}
+
+func (c *Client) addRoute(route map[string]interface{}) error {
+	jsonData, err := json.Marshal(route)
 	if err != nil {
 		return err
 	}
@@ -79,7 +118,98 @@ func (c *Client) AddLinkDomain(domain string, target string) error {
 
 	url := fmt.Sprintf("%s/config/apps/http/servers/srv0/routes", c.BaseURL)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	return c.send("POST", url, jsonData)
+}
+
+// RouteExists mengecek apakah route untuk domain sudah terdaftar (via @id)
+func (c *Client) RouteExists(domain string) (bool, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s/id/%s", c.BaseURL, routeID(domain)))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// SwitchUpstream mengarahkan domain ke target baru secara atomic.
+// Jika route sudah ada, kita PATCH via @id (tanpa downtime), jika belum kita tambahkan.
+func (c *Client) SwitchUpstream(domain string, target string) error {
+	return c.SetUpstreams(domain, []Upstream{{Dial: target}}, "")
+}
+
+// SetUpstreams mengganti seluruh upstream sebuah domain (canary / replicas) secara atomic
+func (c *Client) SetUpstreams(domain string, upstreams []Upstream, policy string) error {
+	if len(upstreams) == 0 {
+		return fmt.Errorf("no upstreams for %s", domain)
+	}
+
+	route := buildRoute(domain, upstreams, policy)
+
+	exists, err := c.RouteExists(domain)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return c.addRoute(route)
+	}
+
+	jsonData, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/id/%s", c.BaseURL, routeID(domain))
+	return c.send("PATCH", url, jsonData)
+}
+
+// RemoveRoute deletes the route of a domain (no-op when it doesn't exist)
+func (c *Client) RemoveRoute(domain string) error {
+	exists, err := c.RouteExists(domain)
+	if err != nil || !exists {
+		return err
+	}
+	return c.send("DELETE", fmt.Sprintf("%s/id/%s", c.BaseURL, routeID(domain)), nil)
+}
+
+// Upstreams returns the upstreams a domain currently routes to (nil if the route doesn't exist)
+func (c *Client) Upstreams(domain string) ([]Upstream, error) {
+	resp, err := c.client.Get(fmt.Sprintf("%s/id/%s", c.BaseURL, routeID(domain)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+
+	var route struct {
+		Handle []struct {
+			Routes []struct {
+				Handle []struct {
+					Handler   string     `json:"handler"`
+					Upstreams []Upstream `json:"upstreams"`
+				} `json:"handle"`
+			} `json:"routes"`
+		} `json:"handle"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&route); err != nil {
+		return nil, err
+	}
+
+	for _, h := range route.Handle {
+		for _, sub := range h.Routes {
+			for _, inner := range sub.Handle {
+				if inner.Handler == "reverse_proxy" {
+					return inner.Upstreams, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) send(method string, url string, body []byte) error {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
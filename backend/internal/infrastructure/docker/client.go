@@ -1,14 +1,25 @@
 package docker
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 // Client wraps the official Docker client
@@ -41,25 +52,89 @@ func (c *Client) ListContainers(ctx context.Context) ([]types.Container, error)
 	return c.cli.ContainerList(ctx, container.ListOptions{All: true})
 }
 
-// PullImage pulls the latest version of the image
-func (c *Client) PullImage(ctx context.Context, imageStr string) error {
+// PullProgress is the aggregated download progress of an image pull
+type PullProgress struct {
+	Status  string // Last status line, e.g. "Downloading"
+	Layers  int    // Layers seen so far
+	Done    int    // Layers fully pulled (or already present)
+	Current int64  // Bytes downloaded across layers
+	Total   int64  // Bytes to download across layers (known so far)
+}
+
+// PullImage pulls the latest version of the image.
+// onProgress (optional) is called for every progress message Docker sends.
+func (c *Client) PullImage(ctx context.Context, imageStr string, onProgress func(PullProgress)) error {
 	reader, err := c.cli.ImagePull(ctx, imageStr, image.PullOptions{})
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
-	// Discard output to avoid filling memory/logs, or we could pipe it to a logger
-	// For now, we just wait for it to finish
-	// io.Copy(io.Discard, reader) -> but we need 'io' package
-	// Simple implementation: read until EOF
-	buf := make([]byte, 1024)
+
+	type layer struct {
+		current, total int64
+		done           bool
+	}
+	layers := make(map[string]*layer)
+	var order []string
+
+	decoder := json.NewDecoder(reader)
 	for {
-		_, err := reader.Read(buf)
-		if err != nil {
-			break
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("pull failed: %s", msg.Error.Message)
+		}
+		if onProgress == nil || msg.ID == "" {
+			continue
+		}
+
+		l, ok := layers[msg.ID]
+		if !ok {
+			l = &layer{}
+			layers[msg.ID] = l
+			order = append(order, msg.ID)
 		}
+		if msg.Progress != nil && msg.Status == "Downloading" {
+			l.current, l.total = msg.Progress.Current, msg.Progress.Total
+		}
+		switch msg.Status {
+		case "Download complete":
+			l.current = l.total
+		case "Pull complete", "Already exists":
+			l.current = l.total
+			l.done = true
+		}
+
+		p := PullProgress{Status: msg.Status, Layers: len(order)}
+		for _, id := range order {
+			p.Current += layers[id].current
+			p.Total += layers[id].total
+			if layers[id].done {
+				p.Done++
+			}
+		}
+		onProgress(p)
 	}
-	return nil
+}
+
+// ImageDigest returns the content digest (sha256:...) of a local image.
+// Falls back to the image ID when the image has no registry digest (e.g. local builds).
+func (c *Client) ImageDigest(ctx context.Context, imageStr string) (string, error) {
+	inspect, err := c.cli.ImageInspect(ctx, imageStr)
+	if err != nil {
+		return "", err
+	}
+	for _, rd := range inspect.RepoDigests {
+		if i := strings.Index(rd, "@"); i >= 0 {
+			return rd[i+1:], nil
+		}
+	}
+	return inspect.ID, nil
 }
 
 // RemoveContainer stops and removes a container by name
@@ -70,35 +145,50 @@ func (c *Client) RemoveContainer(ctx context.Context, containerName string) erro
 	return c.cli.ContainerRemove(ctx, containerName, container.RemoveOptions{Force: true})
 }
 
+// RunOptions describes a project container
+type RunOptions struct {
+	Name     string
+	Image    string
+	Network  string
+	Env      []string
+	Binds    []string
+	Labels   map[string]string
+	CPU      float64 // Cores
+	MemoryMB int64
+}
+
 // RunContainer creates and starts a new container attached to a specific network
 // Returns the internal IP address of the container
-func (c *Client) RunContainer(ctx context.Context, containerName string, image string, networkName string, binds []string, cpu float64, memory int64) (string, error) {
+func (c *Client) RunContainer(ctx context.Context, opts RunOptions) (string, error) {
 	// 1. Create Container
 	hostConfig := &container.HostConfig{
-		Binds: binds,
+		Binds:         opts.Binds,
+		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
 	}
 
 	// Apply Resource Limits (Stability Pillar)
-	if cpu > 0 {
-		hostConfig.Resources.NanoCPUs = int64(cpu * 1e9)
+	if opts.CPU > 0 {
+		hostConfig.Resources.NanoCPUs = int64(opts.CPU * 1e9)
 	}
-	if memory > 0 {
-		hostConfig.Resources.Memory = memory * 1024 * 1024 // Convert MB to Bytes
+	if opts.MemoryMB > 0 {
+		hostConfig.Resources.Memory = opts.MemoryMB * 1024 * 1024 // Convert MB to Bytes
 	}
 
 	resp, err := c.cli.ContainerCreate(ctx,
 		&container.Config{
-			Image:    image,
-			Hostname: containerName,
+			Image:    opts.Image,
+			Hostname: opts.Name,
+			Env:      opts.Env,
+			Labels:   opts.Labels,
 		},
 		hostConfig,
 		&network.NetworkingConfig{
 			EndpointsConfig: map[string]*network.EndpointSettings{
-				networkName: {},
+				opts.Network: {},
 			},
 		},
 		nil,
-		containerName,
+		opts.Name,
 	)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container: %w", err)
@@ -116,14 +206,374 @@ func (c *Client) RunContainer(ctx context.Context, containerName string, image s
 	}
 
 	// Get IP from the specified network
-	netSettings, ok := inspect.NetworkSettings.Networks[networkName]
+	netSettings, ok := inspect.NetworkSettings.Networks[opts.Network]
 	if !ok {
-		return "", fmt.Errorf("container started but not connected to network %s", networkName)
+		return "", fmt.Errorf("container started but not connected to network %s", opts.Network)
 	}
 
 	return netSettings.IPAddress, nil
 }
 
+// maxTaskOutput caps the output kept from a one-off container (the tail is kept)
+const maxTaskOutput = 64 * 1024
+
+// TaskResult is the outcome of a one-off container
+type TaskResult struct {
+	ExitCode int
+	Output   string // Combined stdout/stderr, last maxTaskOutput bytes
+	TimedOut bool   // Killed because ctx was done before the command exited
+}
+
+// RunTask runs a shell command in a new one-off container, waits for it to exit
+// and removes it. The container is killed when ctx is done.
+func (c *Client) RunTask(ctx context.Context, opts RunOptions, command string) (TaskResult, error) {
+	hostConfig := &container.HostConfig{Binds: opts.Binds}
+	if opts.CPU > 0 {
+		hostConfig.Resources.NanoCPUs = int64(opts.CPU * 1e9)
+	}
+	if opts.MemoryMB > 0 {
+		hostConfig.Resources.Memory = opts.MemoryMB * 1024 * 1024
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:      opts.Image,
+			Hostname:   opts.Name,
+			Env:        opts.Env,
+			Labels:     opts.Labels,
+			Entrypoint: []string{"/bin/sh", "-c"}, // The image's entrypoint would swallow the command
+			Cmd:        []string{command},
+		},
+		hostConfig,
+		&network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				opts.Network: {},
+			},
+		},
+		nil,
+		opts.Name,
+	)
+	if err != nil {
+		return TaskResult{}, fmt.Errorf("failed to create container: %w", err)
+	}
+	// ctx may already be done here, cleanup must not depend on it
+	defer c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return TaskResult{}, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	var result TaskResult
+	waitCh, errCh := c.cli.ContainerWait(context.Background(), resp.ID, container.WaitConditionNotRunning)
+	select {
+	case status := <-waitCh:
+		result.ExitCode = int(status.StatusCode)
+	case err := <-errCh:
+		return TaskResult{}, fmt.Errorf("failed to wait for container: %w", err)
+	case <-ctx.Done():
+		result.TimedOut = true
+		_ = c.cli.ContainerKill(context.Background(), resp.ID, "KILL")
+		select {
+		case status := <-waitCh:
+			result.ExitCode = int(status.StatusCode)
+		case <-errCh:
+			result.ExitCode = -1
+		}
+	}
+
+	reader, err := c.cli.ContainerLogs(context.Background(), resp.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return result, nil // The exit code is what matters, output is best effort
+	}
+	defer reader.Close()
+
+	var out bytes.Buffer
+	_, _ = stdcopy.StdCopy(&out, &out, reader)
+	result.Output = out.String()
+	if len(result.Output) > maxTaskOutput {
+		result.Output = "[... output truncated ...]\n" + result.Output[len(result.Output)-maxTaskOutput:]
+	}
+	return result, nil
+}
+
+// ExecTTY starts an interactive command with a TTY inside a running container.
+// The returned connection carries raw terminal input/output; close it to end the session.
+func (c *Client) ExecTTY(ctx context.Context, containerName string, cmd []string, env []string) (string, types.HijackedResponse, error) {
+	exec, err := c.cli.ContainerExecCreate(ctx, containerName, container.ExecOptions{
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          env,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return "", types.HijackedResponse{}, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := c.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{Tty: true})
+	if err != nil {
+		return "", types.HijackedResponse{}, fmt.Errorf("failed to attach exec: %w", err)
+	}
+	return exec.ID, attach, nil
+}
+
+// ResizeExec changes the TTY size of an exec session
+func (c *Client) ResizeExec(ctx context.Context, execID string, rows uint, cols uint) error {
+	return c.cli.ContainerExecResize(ctx, execID, container.ResizeOptions{Height: rows, Width: cols})
+}
+
+// ExecExitCode returns the exit code of a finished exec (-1 while still running)
+func (c *Client) ExecExitCode(ctx context.Context, execID string) (int, error) {
+	inspect, err := c.cli.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return -1, err
+	}
+	if inspect.Running {
+		return -1, nil
+	}
+	return inspect.ExitCode, nil
+}
+
+// BuildImage builds an image from a local context directory (Dockerfile at its root).
+// onLine (optional) receives every output line as it is produced; the combined
+// output is also returned so failures can be shown to the user.
+func (c *Client) BuildImage(ctx context.Context, contextPath string, tag string, onLine func(string)) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "build", "--progress", "plain", "-t", tag, contextPath)
+	pipeReader, pipeWriter := io.Pipe()
+	cmd.Stdout = pipeWriter
+	cmd.Stderr = pipeWriter
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("docker build failed: %w", err)
+	}
+	go func() {
+		pipeWriter.CloseWithError(cmd.Wait())
+	}()
+
+	var output strings.Builder
+	scanner := bufio.NewScanner(pipeReader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		output.WriteString(line)
+		output.WriteString("\n")
+		if onLine != nil {
+			onLine(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return output.String(), fmt.Errorf("docker build failed: %w", err)
+	}
+	return output.String(), nil
+}
+
+// RenameContainer renames an existing container (used to promote a "next" container)
+func (c *Client) RenameContainer(ctx context.Context, containerName string, newName string) error {
+	return c.cli.ContainerRename(ctx, containerName, newName)
+}
+
+// InspectContainer returns the low-level state of a container
+func (c *Client) InspectContainer(ctx context.Context, containerName string) (container.InspectResponse, error) {
+	return c.cli.ContainerInspect(ctx, containerName)
+}
+
+// ContainerExists reports whether a container with the given name or ID exists
+func (c *Client) ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	_, err := c.cli.ContainerInspect(ctx, containerName)
+	if err == nil {
+		return true, nil
+	}
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// TailLogs returns the last N lines of a container's stdout/stderr, with Docker's
+// multiplex headers stripped
+func (c *Client) TailLogs(ctx context.Context, containerName string, lines int) (string, error) {
+	reader, err := c.cli.ContainerLogs(ctx, containerName, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var out bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &out, reader); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// LogLine is one demultiplexed log line of a container
+type LogLine struct {
+	Time   time.Time
+	Stream string // stdout, stderr
+	Text   string
+}
+
+// LogOptions selects the log lines of a container
+type LogOptions struct {
+	Since  time.Time // Zero = from the start
+	Until  time.Time // Zero = up to now
+	Tail   int       // Last N lines, 0 = all
+	Follow bool      // Keep streaming new lines until ctx is done
+}
+
+// StreamLogs calls onLine for every log line of a container, oldest first, with
+// Docker's multiplex headers decoded into the stream name. An error from onLine stops the stream.
+func (c *Client) StreamLogs(ctx context.Context, containerName string, opts LogOptions, onLine func(LogLine) error) error {
+	inspect, err := c.cli.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	logOpts := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Follow:     opts.Follow,
+		Tail:       "all",
+	}
+	if opts.Tail > 0 {
+		logOpts.Tail = strconv.Itoa(opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		logOpts.Since = fmt.Sprintf("%d.%09d", opts.Since.Unix(), opts.Since.Nanosecond())
+	}
+	if !opts.Until.IsZero() {
+		logOpts.Until = fmt.Sprintf("%d.%09d", opts.Until.Unix(), opts.Until.Nanosecond())
+	}
+
+	reader, err := c.cli.ContainerLogs(ctx, containerName, logOpts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	emit := func(stream string, raw string) error {
+		line := LogLine{Stream: stream, Text: raw}
+		if ts, text, ok := strings.Cut(raw, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.Time, line.Text = t, text
+			}
+		}
+		return onLine(line)
+	}
+
+	// TTY containers have a single raw stream without multiplex headers
+	if inspect.Config != nil && inspect.Config.Tty {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if err := emit("stdout", strings.TrimRight(scanner.Text(), "\r")); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	// Frames: [stream, 0, 0, 0, size (uint32 BE)] + payload. Long lines span frames.
+	header := make([]byte, 8)
+	partial := map[string]string{}
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		stream := "stdout"
+		if header[0] == byte(stdcopy.Stderr) {
+			stream = "stderr"
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		data := partial[stream] + string(payload)
+		lines := strings.Split(data, "\n")
+		partial[stream] = lines[len(lines)-1]
+		for _, l := range lines[:len(lines)-1] {
+			if err := emit(stream, l); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ContainerStats is a point-in-time resource sample of a container
+type ContainerStats struct {
+	CPUPercent  float64 // Percent of one core (200 = two full cores)
+	MemoryUsage int64   // Bytes, page cache excluded
+	MemoryLimit int64   // Bytes
+	NetworkRx   int64   // Bytes received since start
+	NetworkTx   int64   // Bytes sent since start
+	BlockRead   int64   // Bytes read from block devices since start
+	BlockWrite  int64   // Bytes written to block devices since start
+}
+
+// Stats samples a container's resource usage.
+// Docker needs two CPU readings for a percentage, so this blocks for about a second.
+func (c *Client) Stats(ctx context.Context, containerName string) (ContainerStats, error) {
+	resp, err := c.cli.ContainerStats(ctx, containerName, false)
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ContainerStats{}, err
+	}
+
+	var stats ContainerStats
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	cpus := float64(raw.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// Same as `docker stats`: page cache can be reclaimed, so it doesn't count
+	usage := raw.MemoryStats.Usage
+	if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < usage {
+		usage -= cache
+	}
+	stats.MemoryUsage = int64(usage)
+	stats.MemoryLimit = int64(raw.MemoryStats.Limit)
+
+	for _, n := range raw.Networks {
+		stats.NetworkRx += int64(n.RxBytes)
+		stats.NetworkTx += int64(n.TxBytes)
+	}
+	// cgroup v1 reports "Read"/"Write", v2 lowercase
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += int64(entry.Value)
+		case "write":
+			stats.BlockWrite += int64(entry.Value)
+		}
+	}
+	return stats, nil
+}
+
 // Close closes the transport
 func (c *Client) Close() error {
 	return c.cli.Close()
@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+const (
+	collectInterval = 10 * time.Second // How often new containers are picked up
+	flushInterval   = time.Second
+	flushBatch      = 500
+)
+
+// storedLine is a collected line waiting to be written
+type storedLine struct {
+	Line
+	ProjectID   string
+	Deployment  string
+	ContainerID string
+}
+
+// Collector follows the logs of every project replica into the `log_lines` table, so
+// the logs of a version survive the removal of its containers
+type Collector struct {
+	service *Service
+
+	mu        sync.Mutex
+	following map[string]bool // container ID -> followed
+
+	lines chan storedLine
+}
+
+// StartCollector starts following project containers and the retention janitor
+func (s *Service) StartCollector(retentionInterval time.Duration) *Collector {
+	s.setupSearch()
+
+	c := &Collector{
+		service:   s,
+		following: make(map[string]bool),
+		lines:     make(chan storedLine, 4*flushBatch),
+	}
+	go c.writeLoop()
+	go func() {
+		c.scan()
+		ticker := time.NewTicker(collectInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c.scan()
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.applyRetention()
+		}
+	}()
+	return c
+}
+
+// scan starts a follower for every running project replica not followed yet
+func (c *Collector) scan() {
+	ctx := context.Background()
+	containers, err := c.service.dockerClient.ListContainers(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, ctr := range containers {
+		projectID := ctr.Labels["senvanda.project_id"]
+		if projectID == "" || ctr.State != "running" || orchestrator.IsTask(ctr.Labels) {
+			continue
+		}
+
+		c.mu.Lock()
+		followed := c.following[ctr.ID]
+		c.following[ctr.ID] = true
+		c.mu.Unlock()
+		if followed {
+			continue
+		}
+
+		// A "-next" candidate is renamed to its replica name once promoted
+		name := ""
+		if len(ctr.Names) > 0 {
+			name = strings.TrimSuffix(strings.TrimPrefix(ctr.Names[0], "/"), "-next")
+		}
+		go c.follow(ctx, ctr.ID, name, projectID, ctr.Labels["senvanda.deployment"])
+	}
+}
+
+// follow streams one container until it stops. It resumes after the last stored line,
+// so a restart of the backend neither loses nor duplicates lines.
+func (c *Collector) follow(ctx context.Context, containerID string, name string, projectID string, deploymentID string) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.following, containerID)
+		c.mu.Unlock()
+	}()
+
+	since := c.service.lastCollected(containerID)
+	if !since.IsZero() {
+		since = since.Add(time.Nanosecond)
+	}
+
+	err := c.service.dockerClient.StreamLogs(ctx, containerID, docker.LogOptions{Since: since, Follow: true}, func(l docker.LogLine) error {
+		if l.Time.IsZero() {
+			l.Time = time.Now()
+		}
+		c.lines <- storedLine{
+			Line:        Line{Time: l.Time, Container: name, Stream: l.Stream, Text: l.Text},
+			ProjectID:   projectID,
+			Deployment:  deploymentID,
+			ContainerID: containerID,
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️ Log collector stopped following %s: %v", name, err)
+	}
+}
+
+// writeLoop batches collected lines into the database
+func (c *Collector) writeLoop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storedLine, 0, flushBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.service.store(batch); err != nil {
+			log.Printf("⚠️ Failed to store %d log lines: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case line := <-c.lines:
+			batch = append(batch, line)
+			if len(batch) >= flushBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
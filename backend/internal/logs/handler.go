@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/events"
+)
+
+const defaultTail = 100
+
+// Handler exposes project container logs
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new log handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the log endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/logs/stream", h.handleStream)
+	g.GET("/deploy/:id/logs/search", h.handleSearch)
+}
+
+// parseQuery reads the shared filter parameters (since, until, tail, stream, replica, q)
+func parseQuery(c echo.Context, now time.Time) (Query, error) {
+	var q Query
+	var err error
+	if q.Since, err = ParseTime(c.QueryParam("since"), now); err != nil {
+		return q, err
+	}
+	if q.Until, err = ParseTime(c.QueryParam("until"), now); err != nil {
+		return q, err
+	}
+
+	// A time window returns everything in it unless tail is explicit
+	if q.Since.IsZero() {
+		q.Tail = defaultTail
+	}
+	if t, err := strconv.Atoi(c.QueryParam("tail")); err == nil && t >= 0 {
+		q.Tail = t
+	}
+
+	switch stream := c.QueryParam("stream"); stream {
+	case "", "stdout", "stderr":
+		q.Stream = stream
+	default:
+		return q, fmt.Errorf("stream must be stdout or stderr")
+	}
+	q.Replica, _ = strconv.Atoi(c.QueryParam("replica"))
+	q.Contains = c.QueryParam("q")
+	return q, nil
+}
+
+// handleSearch searches the collected logs of a project, including those of removed containers
+// URL: GET /api/senvanda/deploy/:id/logs/search?q=timeout&since=24h&until=&deployment=&stream=stderr&limit=100&offset=0
+func (h *Handler) handleSearch(c echo.Context) error {
+	if _, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id")); err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	now := time.Now()
+	q := SearchQuery{
+		Text:       c.QueryParam("q"),
+		Deployment: c.QueryParam("deployment"),
+		Stream:     c.QueryParam("stream"),
+		Limit:      100,
+	}
+	var err error
+	if q.Since, err = ParseTime(c.QueryParam("since"), now); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	if q.Until, err = ParseTime(c.QueryParam("until"), now); err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 1000 {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(c.QueryParam("offset")); err == nil && o > 0 {
+		q.Offset = o
+	}
+
+	lines, err := h.service.Search(c.PathParam("id"), q)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to search logs", err)
+	}
+	return c.JSON(http.StatusOK, lines)
+}
+
+// handleStream sends the matching log lines of every replica as SSE `log` events,
+// interleaved by timestamp. With follow=true it keeps streaming new lines; otherwise
+// it finishes with an `end` event.
+// URL: GET /api/senvanda/deploy/:id/logs/stream?follow=true&since=15m&until=&tail=100&stream=stderr&replica=1&q=error
+func (h *Handler) handleStream(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	now := time.Now()
+	q, err := parseQuery(c, now)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	follow, _ := strconv.ParseBool(c.QueryParam("follow"))
+	if !q.Until.IsZero() {
+		follow = false // A closed window has nothing to follow
+	} else {
+		q.Until = now // History stops where the live part starts, so no line is sent twice
+	}
+
+	ctx := c.Request().Context()
+	past, err := h.service.Read(ctx, project, q)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	stream := events.NewStream(c)
+	for _, line := range past {
+		if err := stream.Send("log", "", line); err != nil {
+			return nil
+		}
+	}
+	if !follow {
+		return stream.Send("end", "", map[string]int{"lines": len(past)})
+	}
+
+	live := make(chan Line, 256)
+	go h.service.Follow(ctx, project, q, now, live)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return nil
+			}
+		case line := <-live:
+			if err := stream.Send("log", "", line); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// rescanInterval is how often a follow picks up replicas started after it began
+const rescanInterval = 10 * time.Second
+
+// Line is one log line of a project container
+type Line struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container"`
+	Stream    string    `json:"stream"` // stdout, stderr
+	Text      string    `json:"text"`
+}
+
+// Query selects and filters log lines
+type Query struct {
+	Since    time.Time // Zero = from the start
+	Until    time.Time // Zero = up to now
+	Tail     int       // Last N lines across all replicas, 0 = all
+	Stream   string    // stdout, stderr, "" = both
+	Replica  int       // 1-based replica, 0 = all
+	Contains string    // Case-insensitive substring
+}
+
+func (q Query) match(l Line) bool {
+	if q.Stream != "" && l.Stream != q.Stream {
+		return false
+	}
+	if q.Contains != "" && !strings.Contains(strings.ToLower(l.Text), strings.ToLower(q.Contains)) {
+		return false
+	}
+	return true
+}
+
+// Service reads and follows the logs of every replica of a project
+type Service struct {
+	app          core.App
+	dockerClient *docker.Client
+	orchestrator *orchestrator.Service
+
+	fts bool // log_lines_fts is available for search
+}
+
+// NewService creates a new log service
+func NewService(app core.App, dockerClient *docker.Client, orchestratorSvc *orchestrator.Service) *Service {
+	return &Service{app: app, dockerClient: dockerClient, orchestrator: orchestratorSvc}
+}
+
+// containers returns the replica containers a query reads from
+func (s *Service) containers(ctx context.Context, project *models.Record, replica int) ([]string, error) {
+	spec, err := orchestrator.SpecFromProject(project)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.orchestrator.ReplicaNames(ctx, project, spec)
+	if err != nil {
+		return nil, err
+	}
+	if replica < 1 {
+		return names, nil
+	}
+	if replica > len(names) {
+		return nil, fmt.Errorf("replica %d is not running (%d running)", replica, len(names))
+	}
+	return names[replica-1 : replica], nil
+}
+
+// Read returns the matching lines of the selected replicas, interleaved by timestamp.
+// Tail is applied per container by Docker and then again over the merged lines.
+func (s *Service) Read(ctx context.Context, project *models.Record, q Query) ([]Line, error) {
+	names, err := s.containers(ctx, project, q.Replica)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		lines    []Line
+		firstErr error
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			var own []Line
+			err := s.dockerClient.StreamLogs(ctx, name, docker.LogOptions{
+				Since: q.Since,
+				Until: q.Until,
+				Tail:  q.Tail,
+			}, func(l docker.LogLine) error {
+				line := Line{Time: l.Time, Container: name, Stream: l.Stream, Text: l.Text}
+				if q.match(line) {
+					own = append(own, line)
+				}
+				return nil
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, own...)
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if firstErr != nil && len(lines) == 0 {
+		return nil, firstErr
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+	if q.Tail > 0 && len(lines) > q.Tail {
+		lines = lines[len(lines)-q.Tail:]
+	}
+	return lines, nil
+}
+
+// Follow streams the lines written after `since` into out until ctx is done.
+// Replicas started later (rollout, scale up) are picked up on the next rescan.
+// Followers are keyed by container ID: a rollout renames the new container to the
+// replica name of the one it replaces, which must not hide it.
+func (s *Service) Follow(ctx context.Context, project *models.Record, q Query, since time.Time, out chan<- Line) error {
+	var mu sync.Mutex
+	following := make(map[string]bool)   // container ID -> followed
+	resume := make(map[string]time.Time) // container ID -> time of the last line sent
+
+	rescan := func() error {
+		names, err := s.containers(ctx, project, q.Replica)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			inspect, err := s.dockerClient.InspectContainer(ctx, name)
+			if err != nil || inspect.State == nil || !inspect.State.Running {
+				continue
+			}
+			id := inspect.ID
+
+			mu.Lock()
+			if following[id] {
+				mu.Unlock()
+				continue
+			}
+			following[id] = true
+			from := since
+			if last, ok := resume[id]; ok && last.After(from) {
+				from = last.Add(time.Nanosecond) // Restarted container: don't repeat what was sent
+			}
+			mu.Unlock()
+
+			go func(id string, name string, from time.Time) {
+				defer func() {
+					mu.Lock()
+					delete(following, id)
+					mu.Unlock()
+				}()
+				_ = s.dockerClient.StreamLogs(ctx, id, docker.LogOptions{Since: from, Follow: true}, func(l docker.LogLine) error {
+					mu.Lock()
+					resume[id] = l.Time
+					mu.Unlock()
+
+					line := Line{Time: l.Time, Container: name, Stream: l.Stream, Text: l.Text}
+					if !q.match(line) {
+						return nil
+					}
+					select {
+					case out <- line:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+			}(id, name, from)
+		}
+		return nil
+	}
+
+	if err := rescan(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(rescanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_ = rescan()
+		}
+	}
+}
+
+// ParseTime accepts RFC3339, unix seconds or a duration relative to now ("15m" = 15 minutes ago)
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use RFC3339, unix seconds or a duration like 15m)", value)
+}
@@ -0,0 +1,176 @@
+package logs
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/security"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// timeLayout keeps nanoseconds so resuming after the last stored line is exact.
+// Same shape as PocketBase dates, so string comparison still orders by time.
+const timeLayout = "2006-01-02 15:04:05.000000000Z"
+
+// setupSearch creates the FTS5 index over log_lines. External content keyed by rowid
+// (PocketBase never VACUUMs the data database, so rowids are stable).
+// SQLite builds without FTS5 fall back to LIKE matching.
+func (s *Service) setupSearch() {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS log_lines_fts USING fts5(text, content='log_lines', content_rowid='rowid')`,
+		`CREATE TRIGGER IF NOT EXISTS log_lines_fts_ai AFTER INSERT ON log_lines BEGIN
+			INSERT INTO log_lines_fts(rowid, text) VALUES (new.rowid, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS log_lines_fts_ad AFTER DELETE ON log_lines BEGIN
+			INSERT INTO log_lines_fts(log_lines_fts, rowid, text) VALUES ('delete', old.rowid, old.text);
+		END`,
+	}
+	for _, stmt := range statements {
+		if _, err := s.app.Dao().DB().NewQuery(stmt).Execute(); err != nil {
+			log.Printf("⚠️ Full-text log search unavailable, falling back to LIKE: %v", err)
+			return
+		}
+	}
+	s.fts = true
+}
+
+// store inserts a batch of collected lines in one transaction (bypassing record
+// hooks and validation, which would be far too slow per log line)
+func (s *Service) store(batch []storedLine) error {
+	return s.app.Dao().RunInTransaction(func(tx *daos.Dao) error {
+		now := time.Now().UTC().Format(timeLayout)
+		for _, l := range batch {
+			_, err := tx.DB().Insert("log_lines", dbx.Params{
+				"id":           security.RandomString(15),
+				"created":      now,
+				"updated":      now,
+				"project":      l.ProjectID,
+				"deployment":   l.Deployment,
+				"container":    l.Container,
+				"container_id": l.ContainerID,
+				"stream":       l.Stream,
+				"time":         l.Time.UTC().Format(timeLayout),
+				"text":         l.Text,
+			}).Execute()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lastCollected returns the time of the newest stored line of a container
+func (s *Service) lastCollected(containerID string) time.Time {
+	var row struct {
+		Time string `db:"time"`
+	}
+	err := s.app.Dao().DB().
+		Select("time").From("log_lines").
+		Where(dbx.HashExp{"container_id": containerID}).
+		OrderBy("time DESC").Limit(1).
+		One(&row)
+	if err != nil || row.Time == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(timeLayout, row.Time)
+	return t
+}
+
+// applyRetention deletes lines past each project's maxAge, then everything beyond its newest maxLines
+func (s *Service) applyRetention() {
+	projects, err := s.app.Dao().FindRecordsByFilter("projects", "id != ''", "", 0, 0)
+	if err != nil {
+		return
+	}
+
+	for _, project := range projects {
+		spec, _ := orchestrator.SpecFromProject(project)
+		retention := spec.LogRetention
+		cutoff := time.Now().Add(-time.Duration(retention.MaxAge)).UTC().Format(timeLayout)
+
+		_, err := s.app.Dao().DB().NewQuery(
+			"DELETE FROM log_lines WHERE project = {:project} AND time < {:cutoff}",
+		).Bind(dbx.Params{"project": project.Id, "cutoff": cutoff}).Execute()
+		if err != nil {
+			log.Printf("⚠️ Log retention for %s failed: %v", spec.Name, err)
+			continue
+		}
+
+		_, err = s.app.Dao().DB().NewQuery(`
+			DELETE FROM log_lines WHERE project = {:project} AND time < (
+				SELECT time FROM log_lines WHERE project = {:project}
+				ORDER BY time DESC LIMIT 1 OFFSET {:max}
+			)`,
+		).Bind(dbx.Params{"project": project.Id, "max": retention.MaxLines - 1}).Execute()
+		if err != nil {
+			log.Printf("⚠️ Log retention for %s failed: %v", spec.Name, err)
+		}
+	}
+}
+
+// SearchQuery filters stored log lines
+type SearchQuery struct {
+	Text       string // Full-text match (FTS5 syntax is not exposed: the text is one phrase)
+	Since      time.Time
+	Until      time.Time
+	Deployment string
+	Stream     string
+	Limit      int
+	Offset     int
+}
+
+// StoredLine is a search hit
+type StoredLine struct {
+	ID         string `db:"id" json:"id"`
+	Deployment string `db:"deployment" json:"deployment"`
+	Container  string `db:"container" json:"container"`
+	Stream     string `db:"stream" json:"stream"`
+	Time       string `db:"time" json:"time"`
+	Text       string `db:"text" json:"text"`
+}
+
+// Search returns stored lines of a project matching the query, newest first
+func (s *Service) Search(projectID string, q SearchQuery) ([]StoredLine, error) {
+	query := s.app.Dao().DB().
+		Select("l.id", "l.deployment", "l.container", "l.stream", "l.time", "l.text").
+		From("log_lines l").
+		Where(dbx.HashExp{"l.project": projectID}).
+		OrderBy("l.time DESC", "l.rowid DESC").
+		Limit(int64(q.Limit)).
+		Offset(int64(q.Offset))
+
+	if q.Text != "" {
+		if s.fts {
+			phrase := `"` + strings.ReplaceAll(q.Text, `"`, `""`) + `"`
+			query.AndWhere(dbx.NewExp(
+				"l.rowid IN (SELECT rowid FROM log_lines_fts WHERE log_lines_fts MATCH {:match})",
+				dbx.Params{"match": phrase},
+			))
+		} else {
+			query.AndWhere(dbx.Like("l.text", q.Text))
+		}
+	}
+	if !q.Since.IsZero() {
+		query.AndWhere(dbx.NewExp("l.time >= {:since}", dbx.Params{"since": q.Since.UTC().Format(timeLayout)}))
+	}
+	if !q.Until.IsZero() {
+		query.AndWhere(dbx.NewExp("l.time <= {:until}", dbx.Params{"until": q.Until.UTC().Format(timeLayout)}))
+	}
+	if q.Deployment != "" {
+		query.AndWhere(dbx.HashExp{"l.deployment": q.Deployment})
+	}
+	if q.Stream != "" {
+		query.AndWhere(dbx.HashExp{"l.stream": q.Stream})
+	}
+
+	lines := []StoredLine{}
+	if err := query.All(&lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
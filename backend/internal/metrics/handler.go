@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/auth"
+)
+
+const defaultRange = 24 * time.Hour
+
+// Handler exposes the resource usage series
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new metrics handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the metrics endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/metrics", h.handleProjectMetrics)
+	g.GET("/metrics", h.handleClusterMetrics, auth.RequireAdmin())
+}
+
+type seriesView struct {
+	Range      string  `json:"range"`
+	Resolution string  `json:"resolution"`
+	Points     []Point `json:"points"`
+}
+
+// handleProjectMetrics returns the usage of a project, summed over its replicas
+// URL: GET /api/senvanda/deploy/:id/metrics?range=24h
+func (h *Handler) handleProjectMetrics(c echo.Context) error {
+	if _, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id")); err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+	return h.respond(c, c.PathParam("id"))
+}
+
+// handleClusterMetrics returns the usage of all projects together (admins only)
+// URL: GET /api/senvanda/metrics?range=7d
+func (h *Handler) handleClusterMetrics(c echo.Context) error {
+	return h.respond(c, "")
+}
+
+func (h *Handler) respond(c echo.Context, projectID string) error {
+	window, err := parseRange(c.QueryParam("range"))
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+	if window > h.service.config.HourRetention {
+		window = h.service.config.HourRetention // Nothing older is kept
+	}
+
+	resolution, points, err := h.service.Series(projectID, window)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to read metrics", err)
+	}
+	return c.JSON(http.StatusOK, seriesView{
+		Range:      window.String(),
+		Resolution: resolution,
+		Points:     points,
+	})
+}
+
+// parseRange accepts Go durations plus a day suffix ("7d")
+func parseRange(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultRange, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid range %q (use a duration like 1h, 24h or 7d)", value)
+}
@@ -0,0 +1,359 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// Resolutions of the stored series. Every sample is folded into both, so the
+// hourly series needs no separate downsampling pass.
+const (
+	ResolutionMinute = "1m"
+	ResolutionHour   = "1h"
+)
+
+// Config controls sampling and retention
+type Config struct {
+	Interval        time.Duration // Sampling interval, default 30s
+	MinuteRetention time.Duration // How long 1m points are kept, default 48h
+	HourRetention   time.Duration // How long 1h points are kept, default 30 days
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.MinuteRetention <= 0 {
+		c.MinuteRetention = 48 * time.Hour
+	}
+	if c.HourRetention <= 0 {
+		c.HourRetention = 30 * 24 * time.Hour
+	}
+	return c
+}
+
+// Point is one bucket of a project's (or the cluster's) usage, summed over replicas
+type Point struct {
+	Time             string  `json:"time"`
+	CPUPercent       float64 `json:"cpu_percent"` // Percent of one core
+	MemoryBytes      float64 `json:"memory_bytes"`
+	MemoryLimit      float64 `json:"memory_limit"`
+	NetRxPerSec      float64 `json:"net_rx_bps"` // Bytes per second
+	NetTxPerSec      float64 `json:"net_tx_bps"`
+	BlockReadPerSec  float64 `json:"block_read_bps"`
+	BlockWritePerSec float64 `json:"block_write_bps"`
+}
+
+// sample is the usage of one project at one instant
+type sample struct {
+	cpu, memory, memoryLimit            float64
+	netRx, netTx, blockRead, blockWrite float64 // Per second
+}
+
+// counters are the cumulative values of a container, kept to turn them into rates
+type counters struct {
+	at                                  time.Time
+	netRx, netTx, blockRead, blockWrite int64
+}
+
+// Service samples Docker stats of every managed container into downsampled series
+type Service struct {
+	app          core.App
+	dockerClient *docker.Client
+	config       Config
+
+	mu   sync.Mutex
+	prev map[string]counters // container ID -> last counters
+}
+
+// NewService creates the metrics collector
+func NewService(app core.App, dockerClient *docker.Client, config Config) *Service {
+	return &Service{
+		app:          app,
+		dockerClient: dockerClient,
+		config:       config.withDefaults(),
+		prev:         make(map[string]counters),
+	}
+}
+
+// Start runs the sampling loop and the hourly retention janitor in the background
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.collect(context.Background()); err != nil {
+				log.Printf("⚠️ Metrics collection failed: %v", err)
+			}
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.applyRetention()
+		}
+	}()
+}
+
+// collect samples every running project container and folds the per-project sums into the series
+func (s *Service) collect(ctx context.Context) error {
+	projects, err := s.app.Dao().FindRecordsByFilter("projects", "id != ''", "", 0, 0)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string) // Containers from before labels existed
+	for _, p := range projects {
+		byName[orchestrator.ContainerName(p.GetString("name"))] = p.Id
+	}
+
+	containers, err := s.dockerClient.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		samples = make(map[string]*sample)
+		seen    = make(map[string]bool)
+	)
+	for _, c := range containers {
+		if c.State != "running" || orchestrator.IsTask(c.Labels) {
+			continue
+		}
+		projectID := c.Labels["senvanda.project_id"]
+		if projectID == "" && len(c.Names) > 0 {
+			projectID = byName[strings.TrimPrefix(c.Names[0], "/")]
+		}
+		if projectID == "" {
+			continue
+		}
+		seen[c.ID] = true
+
+		wg.Add(1)
+		go func(containerID string, projectID string) {
+			defer wg.Done()
+			stats, err := s.dockerClient.Stats(ctx, containerID)
+			if err != nil {
+				return
+			}
+			rates := s.rates(containerID, stats)
+
+			mu.Lock()
+			defer mu.Unlock()
+			sm, ok := samples[projectID]
+			if !ok {
+				sm = &sample{}
+				samples[projectID] = sm
+			}
+			sm.cpu += stats.CPUPercent
+			sm.memory += float64(stats.MemoryUsage)
+			sm.memoryLimit += float64(stats.MemoryLimit)
+			sm.netRx += rates.netRx
+			sm.netTx += rates.netTx
+			sm.blockRead += rates.blockRead
+			sm.blockWrite += rates.blockWrite
+		}(c.ID, projectID)
+	}
+	wg.Wait()
+
+	// Forget counters of containers that are gone
+	s.mu.Lock()
+	for id := range s.prev {
+		if !seen[id] {
+			delete(s.prev, id)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.store(time.Now().UTC(), samples)
+}
+
+// rates turns cumulative counters into per-second rates (zero on the first sample)
+func (s *Service) rates(containerID string, stats docker.ContainerStats) sample {
+	now := time.Now()
+	s.mu.Lock()
+	prev, ok := s.prev[containerID]
+	s.prev[containerID] = counters{
+		at:         now,
+		netRx:      stats.NetworkRx,
+		netTx:      stats.NetworkTx,
+		blockRead:  stats.BlockRead,
+		blockWrite: stats.BlockWrite,
+	}
+	s.mu.Unlock()
+
+	elapsed := now.Sub(prev.at).Seconds()
+	if !ok || elapsed <= 0 {
+		return sample{}
+	}
+	rate := func(cur, old int64) float64 {
+		if cur < old {
+			return 0 // Counter reset (container restarted)
+		}
+		return float64(cur-old) / elapsed
+	}
+	return sample{
+		netRx:      rate(stats.NetworkRx, prev.netRx),
+		netTx:      rate(stats.NetworkTx, prev.netTx),
+		blockRead:  rate(stats.BlockRead, prev.blockRead),
+		blockWrite: rate(stats.BlockWrite, prev.blockWrite),
+	}
+}
+
+// store adds the samples to the minute and hour buckets. Buckets hold sums and a
+// sample count; averages are computed when reading.
+func (s *Service) store(now time.Time, samples map[string]*sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	buckets := map[string]string{
+		ResolutionMinute: bucketTime(now.Truncate(time.Minute)),
+		ResolutionHour:   bucketTime(now.Truncate(time.Hour)),
+	}
+
+	return s.app.Dao().RunInTransaction(func(tx *daos.Dao) error {
+		stamp := types.NowDateTime().String()
+		for projectID, sm := range samples {
+			for resolution, bucket := range buckets {
+				_, err := tx.DB().NewQuery(`
+					INSERT INTO metric_points (id, created, updated, project, resolution, bucket, samples,
+						cpu, memory, memory_limit, net_rx, net_tx, block_read, block_write)
+					VALUES ({:id}, {:stamp}, {:stamp}, {:project}, {:resolution}, {:bucket}, 1,
+						{:cpu}, {:memory}, {:memory_limit}, {:net_rx}, {:net_tx}, {:block_read}, {:block_write})
+					ON CONFLICT (project, resolution, bucket) DO UPDATE SET
+						updated = excluded.updated,
+						samples = samples + 1,
+						cpu = cpu + excluded.cpu,
+						memory = memory + excluded.memory,
+						memory_limit = memory_limit + excluded.memory_limit,
+						net_rx = net_rx + excluded.net_rx,
+						net_tx = net_tx + excluded.net_tx,
+						block_read = block_read + excluded.block_read,
+						block_write = block_write + excluded.block_write`,
+				).Bind(dbx.Params{
+					"id":           security.RandomString(15),
+					"stamp":        stamp,
+					"project":      projectID,
+					"resolution":   resolution,
+					"bucket":       bucket,
+					"cpu":          sm.cpu,
+					"memory":       sm.memory,
+					"memory_limit": sm.memoryLimit,
+					"net_rx":       sm.netRx,
+					"net_tx":       sm.netTx,
+					"block_read":   sm.blockRead,
+					"block_write":  sm.blockWrite,
+				}).Execute()
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Service) applyRetention() {
+	for resolution, keep := range map[string]time.Duration{
+		ResolutionMinute: s.config.MinuteRetention,
+		ResolutionHour:   s.config.HourRetention,
+	} {
+		_, err := s.app.Dao().DB().NewQuery(
+			"DELETE FROM metric_points WHERE resolution = {:resolution} AND bucket < {:cutoff}",
+		).Bind(dbx.Params{
+			"resolution": resolution,
+			"cutoff":     bucketTime(time.Now().UTC().Add(-keep)),
+		}).Execute()
+		if err != nil {
+			log.Printf("⚠️ Metrics retention (%s) failed: %v", resolution, err)
+		}
+	}
+}
+
+// ResolutionFor picks the finest series that still covers the range
+func (s *Service) ResolutionFor(window time.Duration) string {
+	if window <= s.config.MinuteRetention && window <= 24*time.Hour {
+		return ResolutionMinute
+	}
+	return ResolutionHour
+}
+
+// Series returns the points of a project over the last `window`. An empty
+// projectID returns the cluster-wide aggregate (all projects summed per bucket).
+func (s *Service) Series(projectID string, window time.Duration) (string, []Point, error) {
+	resolution := s.ResolutionFor(window)
+	from := bucketTime(time.Now().UTC().Add(-window))
+
+	where := "resolution = {:resolution} AND bucket >= {:from}"
+	if projectID != "" {
+		where += " AND project = {:project}"
+	}
+
+	// Average each project's bucket over its samples first, then sum across projects.
+	// Whole numbers are stored as INTEGER, hence the 1.0 to avoid integer division.
+	var rows []struct {
+		Bucket     string  `db:"bucket"`
+		CPU        float64 `db:"cpu"`
+		Memory     float64 `db:"memory"`
+		MemLimit   float64 `db:"memory_limit"`
+		NetRx      float64 `db:"net_rx"`
+		NetTx      float64 `db:"net_tx"`
+		BlockRead  float64 `db:"block_read"`
+		BlockWrite float64 `db:"block_write"`
+	}
+	err := s.app.Dao().DB().NewQuery(fmt.Sprintf(`
+		SELECT bucket,
+			SUM(1.0 * cpu / samples) AS cpu,
+			SUM(1.0 * memory / samples) AS memory,
+			SUM(1.0 * memory_limit / samples) AS memory_limit,
+			SUM(1.0 * net_rx / samples) AS net_rx,
+			SUM(1.0 * net_tx / samples) AS net_tx,
+			SUM(1.0 * block_read / samples) AS block_read,
+			SUM(1.0 * block_write / samples) AS block_write
+		FROM metric_points
+		WHERE %s
+		GROUP BY bucket
+		ORDER BY bucket`, where),
+	).Bind(dbx.Params{
+		"resolution": resolution,
+		"from":       from,
+		"project":    projectID,
+	}).All(&rows)
+	if err != nil {
+		return resolution, nil, err
+	}
+
+	points := make([]Point, 0, len(rows))
+	for _, r := range rows {
+		points = append(points, Point{
+			Time:             r.Bucket,
+			CPUPercent:       r.CPU,
+			MemoryBytes:      r.Memory,
+			MemoryLimit:      r.MemLimit,
+			NetRxPerSec:      r.NetRx,
+			NetTxPerSec:      r.NetTx,
+			BlockReadPerSec:  r.BlockRead,
+			BlockWritePerSec: r.BlockWrite,
+		})
+	}
+	return resolution, points, nil
+}
+
+func bucketTime(t time.Time) string {
+	return t.UTC().Format(types.DefaultDateLayout)
+}
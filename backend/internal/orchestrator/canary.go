@@ -0,0 +1,306 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/senvanda/backend/internal/events"
+	"github.com/senvanda/backend/internal/infrastructure/caddy"
+)
+
+const canaryProbeInterval = 5 * time.Second
+
+// canaryManualTimeout bounds a step waiting for a manual promote: the project can't
+// deploy meanwhile, so an unattended canary aborts instead of blocking it forever
+const canaryManualTimeout = 30 * time.Minute
+
+// ErrNoCanary is returned by promote/abort when the project has no canary in progress
+var ErrNoCanary = errors.New("no canary in progress for this project")
+
+// CanaryStep is one stage of the weight schedule: send Weight% of traffic to the
+// new release, then wait Pause before moving on. A zero Pause waits for a manual promote
+// and aborts the canary when none comes within canaryManualTimeout.
+type CanaryStep struct {
+	Weight int      `json:"weight"`
+	Pause  Duration `json:"pause"`
+}
+
+// Canary is the `canary` block of the project settings, e.g.
+// {"enabled": true, "steps": [{"weight": 10, "pause": "5m"}, {"weight": 50, "pause": "10m"}, {"weight": 100}]}
+type Canary struct {
+	Enabled bool         `json:"enabled"`
+	Steps   []CanaryStep `json:"steps"`
+}
+
+// withDefaults clamps the weights and makes sure the schedule ends at 100%
+func (c Canary) withDefaults() Canary {
+	if len(c.Steps) == 0 {
+		c.Steps = []CanaryStep{
+			{Weight: 10, Pause: Duration(5 * time.Minute)},
+			{Weight: 50, Pause: Duration(5 * time.Minute)},
+		}
+	}
+
+	steps := make([]CanaryStep, 0, len(c.Steps)+1)
+	for _, step := range c.Steps {
+		if step.Weight <= 0 {
+			continue
+		}
+		if step.Weight >= 100 {
+			break
+		}
+		steps = append(steps, step)
+	}
+	c.Steps = append(steps, CanaryStep{Weight: 100})
+	return c
+}
+
+// CanaryStatus is the live state of a running canary
+type CanaryStatus struct {
+	ProjectID    string     `json:"project_id"`
+	DeploymentID string     `json:"deployment_id"`
+	Step         int        `json:"step"` // 1-based
+	Steps        int        `json:"steps"`
+	Weight       int        `json:"weight"`
+	Stable       []string   `json:"stable"`
+	Canary       string     `json:"canary"`
+	StartedAt    time.Time  `json:"started_at"`
+	ResumeAt     *time.Time `json:"resume_at,omitempty"` // nil = waiting for a manual promote
+	AbortAt      *time.Time `json:"abort_at,omitempty"`  // Manual steps abort unless promoted by then
+}
+
+type canarySignal struct {
+	promote bool
+	reason  string
+}
+
+// canaryRun is the control handle of one in-flight canary
+type canaryRun struct {
+	mu     sync.Mutex
+	status CanaryStatus
+	signal chan canarySignal
+}
+
+func (c *canaryRun) update(fn func(st *CanaryStatus)) {
+	c.mu.Lock()
+	fn(&c.status)
+	c.mu.Unlock()
+}
+
+// CanaryStatus returns the state of the project's running canary, if any
+func (s *Service) CanaryStatus(projectID string) (CanaryStatus, bool) {
+	s.canaryMu.Lock()
+	run, ok := s.canaries[projectID]
+	s.canaryMu.Unlock()
+	if !ok {
+		return CanaryStatus{}, false
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.status, true
+}
+
+// PromoteCanary skips the remaining pauses and sends all traffic to the new release
+func (s *Service) PromoteCanary(projectID string) error {
+	return s.signalCanary(projectID, canarySignal{promote: true})
+}
+
+// AbortCanary sends all traffic back to the stable release and fails the deployment
+func (s *Service) AbortCanary(projectID string, reason string) error {
+	if reason == "" {
+		reason = "aborted manually"
+	}
+	return s.signalCanary(projectID, canarySignal{reason: reason})
+}
+
+func (s *Service) signalCanary(projectID string, sig canarySignal) error {
+	s.canaryMu.Lock()
+	run, ok := s.canaries[projectID]
+	s.canaryMu.Unlock()
+	if !ok {
+		return ErrNoCanary
+	}
+
+	select {
+	case run.signal <- sig:
+		return nil
+	default:
+		return fmt.Errorf("canary is already being promoted or aborted")
+	}
+}
+
+// runCanary shifts traffic to the candidate following the weight schedule.
+// The candidate is probed throughout; a failed probe or a manual abort puts all
+// traffic back on the stable replicas and fails the deployment.
+func (s *Service) runCanary(ctx context.Context, r *Release, stable []string) error {
+	schedule := r.Spec.Canary.withDefaults()
+
+	run := &canaryRun{
+		signal: make(chan canarySignal, 1),
+		status: CanaryStatus{
+			ProjectID: r.Project.Id,
+			Steps:     len(schedule.Steps),
+			Stable:    stable,
+			Canary:    r.Target,
+			StartedAt: time.Now(),
+		},
+	}
+	if r.Deployment != nil {
+		run.status.DeploymentID = r.Deployment.Id
+	}
+
+	s.canaryMu.Lock()
+	s.canaries[r.Project.Id] = run
+	s.canaryMu.Unlock()
+	defer func() {
+		s.canaryMu.Lock()
+		delete(s.canaries, r.Project.Id)
+		s.canaryMu.Unlock()
+	}()
+
+	// A canary mostly waits: give the queue slot back so it doesn't hold up other
+	// projects' deploys. This project stays locked until the canary ends.
+	if s.jobs != nil {
+		s.jobs.ReleaseSlot(r.Project.Id)
+	}
+
+	for i, step := range schedule.Steps {
+		if step.Weight >= 100 {
+			break
+		}
+
+		log.Printf("🐤 Canary %s: %d%% -> %s", r.Spec.Name, step.Weight, r.Target)
+		// Weights are relative: every stable replica gets 100-w, the canary w per stable replica
+		upstreams := []caddy.Upstream{{Dial: r.Target, Weight: step.Weight * len(stable)}}
+		for _, target := range stable {
+			upstreams = append(upstreams, caddy.Upstream{Dial: target, Weight: 100 - step.Weight})
+		}
+		err := s.caddyClient.SetUpstreams(r.Spec.Domain, upstreams, caddy.PolicyWeighted)
+		if err != nil {
+			s.revertCanary(r, stable)
+			return fmt.Errorf("failed to configure Caddy: %w", err)
+		}
+
+		var resumeAt, abortAt *time.Time
+		if step.Pause > 0 {
+			t := time.Now().Add(time.Duration(step.Pause))
+			resumeAt = &t
+		} else {
+			t := time.Now().Add(canaryManualTimeout)
+			abortAt = &t
+		}
+		run.update(func(st *CanaryStatus) {
+			st.Step, st.Weight, st.ResumeAt, st.AbortAt = i+1, step.Weight, resumeAt, abortAt
+		})
+
+		s.setAction(r.Project, fmt.Sprintf("🐤 Canary at %d%% traffic...", step.Weight))
+		r.Events.Emit(events.TypeCanaryStep, "route", fmt.Sprintf("%d%% of traffic -> %s", step.Weight, r.Target), map[string]interface{}{
+			"step":      i + 1,
+			"steps":     len(schedule.Steps),
+			"weight":    step.Weight,
+			"stable":    stable,
+			"canary":    r.Target,
+			"pause_sec": time.Duration(step.Pause).Seconds(),
+		})
+
+		promoted, err := s.watchCanary(ctx, r, run, step)
+		if err != nil {
+			s.revertCanary(r, stable)
+			r.Events.Emit(events.TypeCanaryAborted, "route", err.Error(), map[string]interface{}{
+				"weight": step.Weight,
+				"stable": stable,
+			})
+			return fmt.Errorf("canary aborted at %d%%: %w", step.Weight, err)
+		}
+		if promoted {
+			r.Events.Emit(events.TypeCanaryPromoted, "route", "Canary promoted manually", map[string]interface{}{
+				"weight": step.Weight,
+			})
+			break
+		}
+	}
+
+	run.update(func(st *CanaryStatus) {
+		st.Step, st.Weight, st.ResumeAt, st.AbortAt = len(schedule.Steps), 100, nil, nil
+	})
+	return nil
+}
+
+// watchCanary waits out one step's pause while probing the candidate.
+// Returns promoted=true on a manual promote, or an error when the step must abort
+// (including a manual step nobody promoted within canaryManualTimeout).
+func (s *Service) watchCanary(ctx context.Context, r *Release, run *canaryRun, step CanaryStep) (bool, error) {
+	hc := HealthCheck{}.withDefaults()
+	interval := canaryProbeInterval
+	if r.Spec.HealthCheck != nil {
+		hc = *r.Spec.HealthCheck
+		interval = time.Duration(hc.Interval)
+	}
+
+	var pause, manual <-chan time.Time
+	if step.Pause > 0 {
+		timer := time.NewTimer(time.Duration(step.Pause))
+		defer timer.Stop()
+		pause = timer.C
+	} else {
+		timer := time.NewTimer(canaryManualTimeout)
+		defer timer.Stop()
+		manual = timer.C
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-pause:
+			return false, nil
+		case <-manual:
+			return false, fmt.Errorf("not promoted within %s", canaryManualTimeout)
+		case sig := <-run.signal:
+			if sig.promote {
+				return true, nil
+			}
+			return false, errors.New(sig.reason)
+		case <-ticker.C:
+			if err := s.ensureRunning(ctx, r.NextName); err != nil {
+				r.detail = s.diagnose(ctx, r.NextName)
+				return false, fmt.Errorf("canary container stopped: %w", err)
+			}
+
+			ok, detail := probeOnce(r.Spec.HealthCheck, r.Target)
+			if ok {
+				failures = 0
+				continue
+			}
+
+			failures++
+			r.Events.Emit(events.TypeHealthProbe, "route", detail, map[string]interface{}{
+				"ok":       false,
+				"failures": failures,
+				"target":   r.Target,
+			})
+			if failures >= hc.Retries {
+				r.detail = s.diagnose(ctx, r.NextName)
+				return false, fmt.Errorf("health probe failed %d times in a row (last: %s)", failures, detail)
+			}
+		}
+	}
+}
+
+// revertCanary puts all traffic back on the stable replicas
+func (s *Service) revertCanary(r *Release, stable []string) {
+	log.Printf("↩️ Canary %s aborted, routing all traffic back to %s", r.Spec.Name, strings.Join(stable, ", "))
+	if err := s.routeTo(r, stable); err != nil {
+		log.Printf("⚠️ Failed to revert canary route for %s: %v", r.Spec.Domain, err)
+	}
+}
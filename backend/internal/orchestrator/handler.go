@@ -1,34 +1,47 @@
 package orchestrator
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/labstack/echo/v5"
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/queue"
 )
 
 type DeploymentHandler struct {
 	service *Service
+	jobs    *queue.Service
 }
 
-func NewDeploymentHandler(service *Service) *DeploymentHandler {
-	return &DeploymentHandler{service: service}
+func NewDeploymentHandler(service *Service, jobs *queue.Service) *DeploymentHandler {
+	return &DeploymentHandler{service: service, jobs: jobs}
 }
 
 type DeployFinalPayload struct {
 	ProjectID string `json:"project_id"`
 	ImageTag  string `json:"image_tag"`
 	Status    string `json:"status"`
+
+	// Optional build metadata for the deployment history
+	CommitSHA   string `json:"commit_sha"`
+	BuildNumber int    `json:"build_number"`
 }
 
 func (h *DeploymentHandler) HandleDeployFinal(c echo.Context) error {
+	audit.SetAction(c, "ci.deploy_final")
+
 	// 1. Security Check (The Mantra)
 	secret := c.Request().Header.Get("X-Senvanda-Secret")
 	if secret == "" || secret != os.Getenv("SENVANDA_SHARED_SECRET") {
 		log.Printf("⛔ Unauthorized Deploy attempt from %s", c.RealIP())
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid secret"})
 	}
+	audit.SetActor(c, audit.ActorCI, "", "shared secret")
 
 	// 2. Parse Payload
 	var payload DeployFinalPayload
@@ -36,6 +49,12 @@ func (h *DeploymentHandler) HandleDeployFinal(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
 
+	audit.SetProject(c, payload.ProjectID)
+	audit.Param(c, "status", payload.Status)
+	audit.Param(c, "image_tag", payload.ImageTag)
+	audit.Param(c, "commit_sha", payload.CommitSHA)
+	audit.Param(c, "build_number", payload.BuildNumber)
+
 	// 3. Fetch Project from DB
 	project, err := h.service.app.Dao().FindRecordById("projects", payload.ProjectID)
 	if err != nil {
@@ -49,8 +68,8 @@ func (h *DeploymentHandler) HandleDeployFinal(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"message": "build failure recorded"})
 	}
 
-	// 4. Execute Final Deployment (Asynchronous)
-	// Kita pindahkan ke goroutine agar Woodpecker tidak nunggu lama
+	// 4. Queue Final Deployment (Asynchronous)
+	// Job queue menjamin hanya satu deploy aktif per project, Woodpecker tidak perlu nunggu
 
 	// Construct Full Image Name
 	// Format: [registry/]owner/repo:tag
@@ -62,17 +81,168 @@ func (h *DeploymentHandler) HandleDeployFinal(c echo.Context) error {
 		fullImage = registryHost + "/" + baseImage
 	}
 
-	go func() {
-		if err := h.service.DeployUserApp(project, fullImage); err != nil {
-			log.Printf("❌ Final Deployment failure for %s: %v", project.GetString("name"), err)
+	params := DeployJobParams{
+		Image:       fullImage,
+		CommitSHA:   payload.CommitSHA,
+		BuildNumber: payload.BuildNumber,
+	}
+	if params.BuildNumber == 0 {
+		params.BuildNumber = project.GetInt("last_build_num")
+	}
+
+	job, err := h.jobs.Enqueue(project.Id, queue.KindDeploy, history.TriggerCI, map[string]any{
+		"image":        params.Image,
+		"commit_sha":   params.CommitSHA,
+		"build_number": params.BuildNumber,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to queue deployment for %s: %v", project.GetString("name"), err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to queue deployment"})
+	}
+
+	audit.Queued(c, job.Id)
+	return c.JSON(http.StatusAccepted, queue.JobResponse(job, "Victory! Deployment queued."))
+}
+
+type RollbackPayload struct {
+	DeploymentID string `json:"deployment_id"` // Optional, defaults to the previous successful release
+}
+
+// HandleRollback restores an earlier successful deployment
+// URL: POST /api/senvanda/deploy/:id/rollback
+func (h *DeploymentHandler) HandleRollback(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "project not found"})
+	}
+
+	audit.SetAction(c, "project.rollback")
+	var payload RollbackPayload
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	audit.Param(c, "deployment_id", payload.DeploymentID)
+
+	// Resolve now so a bad target fails fast instead of inside the queue
+	target, err := h.service.history.FindRollbackTarget(project.Id, payload.DeploymentID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	job, err := h.jobs.Enqueue(project.Id, queue.KindRollback, history.TriggerRollback, map[string]any{
+		"deployment_id": target.Id,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to queue rollback"})
+	}
+
+	audit.Queued(c, job.Id)
+	resp := queue.JobResponse(job, "Rollback queued.")
+	resp["rollback_of"] = target.Id
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+type ScalePayload struct {
+	Replicas int `json:"replicas"`
+}
+
+// HandleScale changes the number of replicas without a full redeploy
+// URL: POST /api/senvanda/deploy/:id/scale
+func (h *DeploymentHandler) HandleScale(c echo.Context) error {
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "project not found"})
+	}
+
+	audit.SetAction(c, "project.scale")
+	var payload ScalePayload
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	audit.Param(c, "replicas", payload.Replicas)
+	if payload.Replicas < 1 || payload.Replicas > MaxReplicas {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("replicas must be between 1 and %d", MaxReplicas)})
+	}
+
+	// Scaling up must fit the owner's quota (scaling down is always allowed)
+	if spec, err := SpecFromProject(project); err == nil && payload.Replicas > spec.Replicas {
+		spec.Replicas = payload.Replicas
+		if err := h.service.Admit(project, spec); err != nil {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
 		}
-	}()
+	}
 
-	return c.JSON(http.StatusAccepted, map[string]string{
-		"message": "Victory! Deployment process started.",
+	// Persist first so a deploy queued after this keeps the new scale
+	if err := h.service.SetReplicas(project, payload.Replicas); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	job, err := h.jobs.Enqueue(project.Id, queue.KindScale, history.TriggerManual, map[string]any{
+		"replicas": payload.Replicas,
 	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to queue scale"})
+	}
+
+	audit.Queued(c, job.Id)
+	return c.JSON(http.StatusAccepted, queue.JobResponse(job, fmt.Sprintf("Scaling to %d replicas.", payload.Replicas)))
 }
 
-func (h *DeploymentHandler) RegisterRoutes(g *echo.Group) {
+// HandleCanaryStatus returns the running canary of a project
+// URL: GET /api/senvanda/deploy/:id/canary
+func (h *DeploymentHandler) HandleCanaryStatus(c echo.Context) error {
+	status, ok := h.service.CanaryStatus(c.PathParam("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": ErrNoCanary.Error()})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// HandleCanaryPromote sends all traffic to the canary, skipping the remaining steps
+// URL: POST /api/senvanda/deploy/:id/canary/promote
+func (h *DeploymentHandler) HandleCanaryPromote(c echo.Context) error {
+	audit.SetAction(c, "project.canary_promote")
+	if err := h.service.PromoteCanary(c.PathParam("id")); err != nil {
+		return canaryError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]string{"message": "Canary promotion requested."})
+}
+
+type CanaryAbortPayload struct {
+	Reason string `json:"reason"`
+}
+
+// HandleCanaryAbort routes all traffic back to the stable release and fails the deployment
+// URL: POST /api/senvanda/deploy/:id/canary/abort
+func (h *DeploymentHandler) HandleCanaryAbort(c echo.Context) error {
+	audit.SetAction(c, "project.canary_abort")
+	var payload CanaryAbortPayload
+	_ = c.Bind(&payload) // Body is optional
+	audit.Param(c, "reason", payload.Reason)
+
+	if err := h.service.AbortCanary(c.PathParam("id"), payload.Reason); err != nil {
+		return canaryError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]string{"message": "Canary abort requested."})
+}
+
+func canaryError(c echo.Context, err error) error {
+	if errors.Is(err, ErrNoCanary) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+}
+
+// RegisterPublicRoutes registers the CI callback, authenticated by the shared secret
+func (h *DeploymentHandler) RegisterPublicRoutes(g *echo.Group) {
 	g.POST("/deploy-final", h.HandleDeployFinal)
 }
+
+// RegisterRoutes registers the project routes (behind auth and ownership)
+func (h *DeploymentHandler) RegisterRoutes(g *echo.Group) {
+	g.POST("/deploy/:id/rollback", h.HandleRollback)
+	g.POST("/deploy/:id/scale", h.HandleScale)
+	g.GET("/deploy/:id/canary", h.HandleCanaryStatus)
+	g.POST("/deploy/:id/canary/promote", h.HandleCanaryPromote)
+	g.POST("/deploy/:id/canary/abort", h.HandleCanaryAbort)
+}
@@ -0,0 +1,207 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	readinessTimeout   = 60 * time.Second
+	readinessInterval  = 1 * time.Second
+	diagnosticLogLines = 50
+)
+
+// HealthCheck is the `healthCheck` block of the project settings.
+// The orchestrator only routes traffic to a container after this probe passes.
+type HealthCheck struct {
+	Path           string   `json:"path"`           // e.g. "/healthz"
+	ExpectedStatus int      `json:"expectedStatus"` // 0 = any 2xx/3xx
+	Interval       Duration `json:"interval"`
+	Timeout        Duration `json:"timeout"`
+	Retries        int      `json:"retries"`     // Consecutive failures before giving up
+	StartPeriod    Duration `json:"startPeriod"` // Grace period where failures don't count
+}
+
+// Duration accepts either a Go duration string ("5s") or a number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	switch v := raw.(type) {
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Second)))
+	case string:
+		if v == "" {
+			*d = 0
+			return nil
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration: %s", string(data))
+	}
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// withDefaults fills unset fields with sane values
+func (h HealthCheck) withDefaults() HealthCheck {
+	if h.Path == "" {
+		h.Path = "/"
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		h.Path = "/" + h.Path
+	}
+	if h.Interval <= 0 {
+		h.Interval = Duration(5 * time.Second)
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = Duration(3 * time.Second)
+	}
+	if h.Retries <= 0 {
+		h.Retries = 3
+	}
+	if h.StartPeriod < 0 {
+		h.StartPeriod = 0
+	}
+	return h
+}
+
+func (h HealthCheck) statusOK(code int) bool {
+	if h.ExpectedStatus > 0 {
+		return code == h.ExpectedStatus
+	}
+	return code >= 200 && code < 400
+}
+
+// waitForReady blocks until the new container is ready to take traffic.
+// With a healthCheck configured we probe HTTP, otherwise we wait for the port to accept TCP.
+// onProbe (optional) receives the result of every attempt.
+func (s *Service) waitForReady(ctx context.Context, hc *HealthCheck, containerName string, target string, onProbe ProbeFunc) error {
+	if onProbe == nil {
+		onProbe = func(bool, string) {}
+	}
+	if hc != nil {
+		return s.probeHTTP(ctx, containerName, target, *hc, onProbe)
+	}
+	return s.probeTCP(ctx, containerName, target, readinessTimeout, onProbe)
+}
+
+// ProbeFunc receives the outcome of a single readiness probe attempt
+type ProbeFunc func(ok bool, detail string)
+
+// probeHTTP follows Docker HEALTHCHECK semantics: failures during startPeriod are ignored,
+// afterwards `retries` consecutive failures mark the container unhealthy.
+func (s *Service) probeHTTP(ctx context.Context, containerName string, target string, hc HealthCheck, onProbe ProbeFunc) error {
+	httpClient := &http.Client{Timeout: time.Duration(hc.Timeout)}
+	url := fmt.Sprintf("http://%s%s", target, hc.Path)
+	started := time.Now()
+	failures := 0
+
+	for {
+		if err := s.ensureRunning(ctx, containerName); err != nil {
+			return err
+		}
+
+		ok, detail := probeHTTPOnce(httpClient, url, hc)
+		onProbe(ok, detail)
+		if ok {
+			return nil
+		}
+
+		if time.Since(started) >= time.Duration(hc.StartPeriod) {
+			failures++
+			if failures >= hc.Retries {
+				return fmt.Errorf("health check failed %d times in a row (last: %s)", failures, detail)
+			}
+		}
+		time.Sleep(time.Duration(hc.Interval))
+	}
+}
+
+func probeHTTPOnce(httpClient *http.Client, url string, hc HealthCheck) (bool, string) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return false, fmt.Sprintf("GET %s: %v", hc.Path, err)
+	}
+	resp.Body.Close()
+	return hc.statusOK(resp.StatusCode), fmt.Sprintf("GET %s returned %d", hc.Path, resp.StatusCode)
+}
+
+// probeOnce runs a single probe against target: HTTP when a healthCheck is configured, TCP otherwise
+func probeOnce(hc *HealthCheck, target string) (bool, string) {
+	if hc != nil {
+		httpClient := &http.Client{Timeout: time.Duration(hc.Timeout)}
+		return probeHTTPOnce(httpClient, fmt.Sprintf("http://%s%s", target, hc.Path), *hc)
+	}
+
+	conn, err := net.DialTimeout("tcp", target, 2*time.Second)
+	if err != nil {
+		return false, fmt.Sprintf("tcp %s: %v", target, err)
+	}
+	conn.Close()
+	return true, fmt.Sprintf("tcp %s accepted connection", target)
+}
+
+// probeTCP waits until the container accepts TCP connections on target.
+// Backend berada di network yang sama dengan app, jadi kita bisa dial IP internal langsung.
+func (s *Service) probeTCP(ctx context.Context, containerName string, target string, timeout time.Duration, onProbe ProbeFunc) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := s.ensureRunning(ctx, containerName); err != nil {
+			return err
+		}
+
+		conn, err := net.DialTimeout("tcp", target, 2*time.Second)
+		if err == nil {
+			conn.Close()
+			onProbe(true, fmt.Sprintf("tcp %s accepted connection", target))
+			return nil
+		}
+		onProbe(false, fmt.Sprintf("tcp %s: %v", target, err))
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s: %v", timeout, target, err)
+		}
+		time.Sleep(readinessInterval)
+	}
+}
+
+func (s *Service) ensureRunning(ctx context.Context, containerName string) error {
+	inspect, err := s.dockerClient.InspectContainer(ctx, containerName)
+	if err != nil {
+		return err
+	}
+	if inspect.State != nil && !inspect.State.Running {
+		return fmt.Errorf("container exited (code %d)", inspect.State.ExitCode)
+	}
+	return nil
+}
+
+// diagnose collects the exit code and last log lines of a failed container for error_log
+func (s *Service) diagnose(ctx context.Context, containerName string) string {
+	var b strings.Builder
+	if inspect, err := s.dockerClient.InspectContainer(ctx, containerName); err == nil && inspect.State != nil {
+		fmt.Fprintf(&b, "Container state: %s (exit code %d)\n", inspect.State.Status, inspect.State.ExitCode)
+	}
+	if logs, err := s.dockerClient.TailLogs(ctx, containerName, diagnosticLogLines); err == nil && logs != "" {
+		fmt.Fprintf(&b, "--- last %d log lines ---\n%s", diagnosticLogLines, logs)
+	}
+	return b.String()
+}
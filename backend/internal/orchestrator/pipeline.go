@@ -0,0 +1,375 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/events"
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+)
+
+// Request describes what a deploy should run
+type Request struct {
+	Image string // Explicit image (CI callback, rollback). Empty = derive from the project
+	Meta  history.Meta
+}
+
+// Release is the state threaded through the pipeline steps of one deployment
+type Release struct {
+	Project    *models.Record
+	Deployment *models.Record
+	Spec       Spec
+	Events     *events.Emitter
+
+	Image       string // Image that will run (explicit, built or configured)
+	Built       bool   // Image was built locally in this release
+	SourceDir   string // Checkout used by the build step
+	NextName    string // Candidate container name during the rollout
+	ContainerIP string
+	Target      string // ip:port of the candidate container
+
+	Live     []replica // Replicas serving before this release
+	Promoted []replica // Replicas of this release that took over
+
+	candidate int    // Replica index of the candidate (0 = none)
+	detail    string // Extra failure context for error_log (build output, diagnostics)
+}
+
+// Step is one ordered phase of the deploy pipeline
+type Step struct {
+	Name   string
+	Action string                // current_action shown in the dashboard
+	When   func(r *Release) bool // nil = always run
+	Run    func(ctx context.Context, r *Release) error
+}
+
+// StepError reports which pipeline step failed
+type StepError struct {
+	Step   string
+	Err    error
+	Detail string
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s step failed: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// pipeline returns the ordered deploy steps.
+// release runs before the candidate starts, so a failing migration leaves the live version untouched.
+// verify runs before route: traffic only switches once the new container is healthy,
+// and each old replica keeps serving until its replacement has taken over.
+func (s *Service) pipeline() []Step {
+	return []Step{
+		{Name: "source", Action: "📥 Fetching source code...", When: needsBuild, Run: s.stepSource},
+		{Name: "build", Action: "🔨 Building image...", When: needsBuild, Run: s.stepBuild},
+		{Name: "pull", Action: "📦 Pulling latest docker image...", Run: s.stepPull},
+		{Name: "release", Action: "🗃️ Running release command...", When: hasReleaseCommand, Run: s.stepRelease},
+		{Name: "run", Action: "▶️ Starting new container...", Run: s.stepRun},
+		{Name: "verify", Action: "🩺 Running health checks...", Run: s.stepVerify},
+		{Name: "route", Action: "📡 Switching traffic to new container...", Run: s.stepRoute},
+		{Name: "rollout", Action: "🔁 Rolling out replicas...", Run: s.stepRollout},
+		{Name: "finalize", Action: "♻️ Retiring old container...", Run: s.stepFinalize},
+	}
+}
+
+// Deploy runs the deploy pipeline for a project. Every entry point (CI callback,
+// dashboard redeploy, token webhook, rollback) ends up here, and every call is
+// recorded as one entry in the `deployments` collection.
+func (s *Service) Deploy(project *models.Record, req Request) error {
+	ctx := context.Background() // Background context for the long-running process
+
+	spec, specErr := SpecFromProject(project)
+
+	req.Meta.Image = req.Image
+	if req.Meta.Image == "" {
+		req.Meta.Image = spec.Image
+	}
+	deployment, _ := s.history.Start(project, req.Meta)
+
+	deploymentID := ""
+	if deployment != nil {
+		deploymentID = deployment.Id
+	}
+
+	release := &Release{
+		Project:    project,
+		Deployment: deployment,
+		Events:     s.events.For(project.Id, deploymentID),
+		Spec:       spec,
+		Image:      req.Image,
+	}
+
+	err := specErr
+	if err == nil {
+		// Settings may have changed since the job was queued
+		err = s.Admit(project, spec)
+	}
+	if err == nil {
+		err = s.runPipeline(ctx, release)
+	}
+
+	if err != nil {
+		reason := err.Error()
+		if release.detail != "" {
+			reason += "\n" + release.detail
+		}
+		s.markFailed(project, reason)
+
+		data := map[string]interface{}{"error": err.Error(), "detail": release.detail}
+		var stepErr *StepError
+		if errors.As(err, &stepErr) {
+			data["phase"] = stepErr.Step
+			data["error"] = stepErr.Err.Error()
+		}
+		release.Events.Emit(events.TypeFailed, "", "❌ Deployment failed", data)
+	} else {
+		release.Events.Emit(events.TypeSucceeded, "", "🎉 Deployment completed", map[string]interface{}{
+			"image": release.Image,
+			"url":   fmt.Sprintf("http://%s", release.Spec.Domain),
+		})
+	}
+
+	s.history.Finish(deployment, err)
+	return err
+}
+
+func (s *Service) runPipeline(ctx context.Context, r *Release) error {
+	log.Printf("🚀 Starting deployment for %s...", r.Spec.Name)
+	r.Project.Set("status", "deploying")
+	s.setAction(r.Project, "🚀 Initializing deployment...")
+
+	defer func() {
+		if r.SourceDir != "" {
+			_ = os.RemoveAll(r.SourceDir)
+		}
+	}()
+
+	for _, step := range s.pipeline() {
+		if step.When != nil && !step.When(r) {
+			continue
+		}
+
+		s.setAction(r.Project, step.Action)
+		r.Events.Emit(events.TypePhaseStarted, step.Name, step.Action, nil)
+		started := time.Now()
+
+		if err := step.Run(ctx, r); err != nil {
+			// Old replicas are only retired once their replacement serves, so dropping the candidate is enough
+			if r.NextName != "" {
+				_ = s.dockerClient.RemoveContainer(ctx, r.NextName)
+			}
+			return &StepError{Step: step.Name, Err: err, Detail: r.detail}
+		}
+
+		r.Events.Emit(events.TypePhaseFinished, step.Name, "", map[string]interface{}{
+			"duration_ms": time.Since(started).Milliseconds(),
+		})
+	}
+
+	log.Printf("🎉 Deployment for %s completed successfully!", r.Spec.Name)
+	return nil
+}
+
+func needsBuild(r *Release) bool {
+	return r.Image == "" && r.Spec.BuildsFromSource()
+}
+
+// stepSource clones the repository for source builds
+func (s *Service) stepSource(ctx context.Context, r *Release) error {
+	r.SourceDir = filepath.Join(os.TempDir(), fmt.Sprintf("senvanda-build-%s-%d", r.Spec.Name, time.Now().UnixNano()))
+
+	args := []string{"clone", "--depth", "1"}
+	if r.Spec.Branch != "" {
+		args = append(args, "--branch", r.Spec.Branch)
+	}
+	args = append(args, r.Spec.RepoURL, r.SourceDir)
+
+	log.Printf("📥 Cloning %s (branch: %s)", r.Spec.RepoURL, r.Spec.Branch)
+	if output, err := exec.CommandContext(ctx, "git", args...).CombinedOutput(); err != nil {
+		r.detail = string(output)
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	return nil
+}
+
+// stepBuild builds the checked-out Dockerfile into a per-deployment tag
+func (s *Service) stepBuild(ctx context.Context, r *Release) error {
+	tag := fmt.Sprintf("senvanda/project-%s:%d", r.Spec.Name, time.Now().Unix())
+	if r.Deployment != nil {
+		tag = fmt.Sprintf("senvanda/project-%s:%s", r.Spec.Name, r.Deployment.Id)
+	}
+
+	log.Printf("🔨 Building %s", tag)
+	output, err := s.dockerClient.BuildImage(ctx, r.SourceDir, tag, func(line string) {
+		r.Events.Emit(events.TypeBuildLog, "build", line, nil)
+	})
+	if err != nil {
+		r.detail = lastLines(output, diagnosticLogLines)
+		return err
+	}
+
+	r.Image = tag
+	r.Built = true
+	return nil
+}
+
+// stepPull resolves the image and pulls it from the registry (unless built locally)
+func (s *Service) stepPull(ctx context.Context, r *Release) error {
+	if r.Image == "" {
+		r.Image = r.Spec.Image
+	}
+	if r.Image == "" || r.Image == CustomBuildImage {
+		r.Image = defaultImage
+	}
+
+	if !r.Built {
+		log.Printf("📦 Pulling image: %s", r.Image)
+		if err := s.dockerClient.PullImage(ctx, r.Image, r.pullProgress()); err != nil {
+			// Registry unreachable, but a cached copy is just as good (rollbacks hit this often)
+			if _, errLocal := s.dockerClient.ImageDigest(ctx, r.Image); errLocal != nil {
+				return fmt.Errorf("failed to pull image: %w", err)
+			}
+			log.Printf("⚠️ Pull failed (%v), using local copy of %s", err, r.Image)
+		}
+	}
+
+	digest, _ := s.dockerClient.ImageDigest(ctx, r.Image)
+	s.history.SetImage(r.Deployment, r.Image, digest)
+	return nil
+}
+
+// stepRun starts the candidate for replica 1 next to the live one (Blue/Green)
+func (s *Service) stepRun(ctx context.Context, r *Release) error {
+	live, err := s.liveReplicas(ctx, r.Project, r.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to list running replicas: %w", err)
+	}
+	r.Live = live
+
+	return s.startReplica(ctx, r, 1)
+}
+
+// stepVerify waits for the candidate to pass its readiness probe
+func (s *Service) stepVerify(ctx context.Context, r *Release) error {
+	log.Printf("🩺 Waiting for %s to become healthy...", r.Target)
+	if err := s.waitForReady(ctx, r.Spec.HealthCheck, r.NextName, r.Target, r.probeReporter(r.candidate)); err != nil {
+		r.detail = s.diagnose(ctx, r.NextName)
+		return err
+	}
+	return nil
+}
+
+// probeReporter emits a health_probe event for every readiness attempt of a replica
+func (r *Release) probeReporter(index int) ProbeFunc {
+	attempt := 0
+	target := r.Target
+	return func(ok bool, detail string) {
+		attempt++
+		r.Events.Emit(events.TypeHealthProbe, "verify", detail, map[string]interface{}{
+			"ok":      ok,
+			"attempt": attempt,
+			"target":  target,
+			"replica": index,
+		})
+	}
+}
+
+// stepRoute moves traffic from the old replica 1 to the candidate, gradually when the
+// project has a canary schedule and there is a live release to compare against.
+// The other old replicas keep serving until the rollout step replaces them.
+func (s *Service) stepRoute(ctx context.Context, r *Release) error {
+	if r.Spec.Canary != nil {
+		if stable := liveTargets(r.Live); len(stable) > 0 {
+			if err := s.runCanary(ctx, r, stable); err != nil {
+				return err
+			}
+		}
+	}
+
+	upstreams := r.upstreams(0)
+	log.Printf("📡 Switching Caddy route for %s -> %s", r.Spec.Domain, strings.Join(upstreams, ", "))
+	if err := s.routeTo(r, upstreams); err != nil {
+		return err
+	}
+	r.Events.Emit(events.TypeRouteSwitched, "route", fmt.Sprintf("%s -> %s", r.Spec.Domain, r.Target), map[string]interface{}{
+		"domain":    r.Spec.Domain,
+		"upstream":  r.Target,
+		"upstreams": upstreams,
+		"container": r.NextName,
+	})
+	return nil
+}
+
+// pullProgress throttles Docker's pull messages into at most one event per second
+// (plus one whenever another layer completes)
+func (r *Release) pullProgress() func(docker.PullProgress) {
+	var last time.Time
+	lastDone := -1
+	return func(p docker.PullProgress) {
+		if time.Since(last) < time.Second && p.Done == lastDone {
+			return
+		}
+		last, lastDone = time.Now(), p.Done
+
+		percent := 0
+		if p.Total > 0 {
+			percent = int(p.Current * 100 / p.Total)
+		}
+		r.Events.Emit(events.TypePullProgress, "pull", p.Status, map[string]interface{}{
+			"layers":  p.Layers,
+			"done":    p.Done,
+			"current": p.Current,
+			"total":   p.Total,
+			"percent": percent,
+		})
+	}
+}
+
+// stepFinalize cleans up containers of the old engines and marks the project online
+func (s *Service) stepFinalize(ctx context.Context, r *Release) error {
+	_ = s.dockerClient.RemoveContainer(ctx, LegacyContainerName(r.Spec.Name)) // Container from the old engine
+
+	if len(r.Promoted) > 0 {
+		first := r.Promoted[0]
+		r.Project.Set("containerId", first.ID)
+		r.Project.Set("internal_ip", first.IP)
+	}
+
+	r.Project.Set("status", "online")
+	r.Project.Set("last_deployed", time.Now())
+	r.Project.Set("url", fmt.Sprintf("http://%s", r.Spec.Domain))
+	r.Project.Set("error_log", "")
+	r.Project.Set("current_action", "") // Clear action on success
+
+	if err := s.app.Dao().SaveRecord(r.Project); err != nil {
+		log.Printf("⚠️ Failed to save final project state: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) setAction(project *models.Record, action string) {
+	project.Set("current_action", action)
+	s.app.Dao().SaveRecord(project)
+}
+
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
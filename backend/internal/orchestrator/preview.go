@@ -0,0 +1,106 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+)
+
+const maxBranchSlug = 30
+
+// Previews is the `previews` block of the project settings: pushes to other branches
+// matching Branches (glob patterns, empty = any) get their own preview environment,
+// removed when the branch is deleted or after TTL without pushes.
+type Previews struct {
+	Enabled  bool     `json:"enabled"`
+	Branches []string `json:"branches"` // e.g. ["feature/*", "fix-*"]
+	TTL      Duration `json:"ttl"`
+}
+
+func (p Previews) withDefaults() Previews {
+	if p.TTL <= 0 {
+		p.TTL = Duration(72 * time.Hour)
+	}
+	return p
+}
+
+// Matches reports whether a branch gets a preview environment
+func (p Previews) Matches(branch string) bool {
+	if len(p.Branches) == 0 {
+		return true
+	}
+	for _, pattern := range p.Branches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// BranchSlug turns a branch into a DNS/container-safe label ("feature/Login" -> "feature-login")
+func BranchSlug(branch string) string {
+	slug := slugInvalid.ReplaceAllString(strings.ToLower(branch), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > maxBranchSlug {
+		slug = strings.TrimRight(slug[:maxBranchSlug], "-")
+	}
+	return slug
+}
+
+// PreviewName is the project name of a branch preview, served at DefaultDomain(PreviewName(...)).
+// Numeric slugs get a "branch-" prefix: the containers of preview "shop-2" would be named
+// like replica 2 of "shop" (senvanda-app-shop-2).
+func PreviewName(projectName string, branch string) string {
+	slug := BranchSlug(branch)
+	if _, err := strconv.Atoi(slug); err == nil {
+		slug = "branch-" + slug
+	}
+	return fmt.Sprintf("%s-%s", projectName, slug)
+}
+
+// Teardown removes every container and the Caddy route of a project, then deletes its record
+// (deployments, jobs and events go with it through the cascading relations)
+func (s *Service) Teardown(ctx context.Context, project *models.Record) error {
+	spec, _ := SpecFromProject(project)
+	log.Printf("🧹 Tearing down %s (%s)", spec.Name, spec.Domain)
+
+	containers, err := s.dockerClient.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		if c.Labels["senvanda.project_id"] == project.Id || isLegacyContainer(c.Labels, name, spec.Name) {
+			if err := s.dockerClient.RemoveContainer(ctx, c.ID); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+		}
+	}
+
+	if err := s.caddyClient.RemoveRoute(spec.Domain); err != nil {
+		return fmt.Errorf("failed to remove Caddy route: %w", err)
+	}
+
+	return s.app.Dao().DeleteRecord(project)
+}
+
+func (s *Service) runTeardownJob(ctx context.Context, job *models.Record) error {
+	project, err := s.app.Dao().FindRecordById("projects", job.GetString("project"))
+	if err != nil {
+		return nil // Already gone
+	}
+	// Deleting the project also deletes this job record; the queue's final save is then a no-op
+	return s.Teardown(ctx, project)
+}
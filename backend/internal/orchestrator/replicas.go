@@ -0,0 +1,415 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/events"
+	"github.com/senvanda/backend/internal/infrastructure/caddy"
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+)
+
+// replica is one container of a project
+type replica struct {
+	Index  int    // 1-based
+	Name   string // Container name
+	ID     string
+	IP     string
+	Target string // ip:port, "" when the container isn't running
+}
+
+// Autoscale is the `autoscale` block of the project settings: the replica count
+// floats between MinReplicas and MaxReplicas based on CPU and/or memory usage.
+type Autoscale struct {
+	Enabled           bool        `json:"enabled"`
+	MinReplicas       int         `json:"minReplicas"`
+	MaxReplicas       int         `json:"maxReplicas"`
+	CPU               *Thresholds `json:"cpu"`    // Percent of the CPU limit
+	Memory            *Thresholds `json:"memory"` // Percent of the memory limit
+	ScaleUpCooldown   Duration    `json:"scaleUpCooldown"`
+	ScaleDownCooldown Duration    `json:"scaleDownCooldown"`
+}
+
+// Thresholds: scale up when average usage reaches ScaleUp, down when it falls to ScaleDown
+type Thresholds struct {
+	ScaleUp   float64 `json:"scaleUp"`
+	ScaleDown float64 `json:"scaleDown"`
+}
+
+// withDefaults clamps the bounds and falls back to CPU 80%/20% with 1m/5m cooldowns
+func (a Autoscale) withDefaults() Autoscale {
+	a.MinReplicas = max(a.MinReplicas, 1)
+	a.MaxReplicas = min(max(a.MaxReplicas, a.MinReplicas), MaxReplicas)
+	a.MinReplicas = min(a.MinReplicas, a.MaxReplicas)
+
+	if a.CPU == nil && a.Memory == nil {
+		a.CPU = &Thresholds{ScaleUp: 80, ScaleDown: 20}
+	}
+	for _, t := range []*Thresholds{a.CPU, a.Memory} {
+		if t != nil && t.ScaleUp <= 0 {
+			t.ScaleUp = 80
+		}
+	}
+	if a.ScaleUpCooldown <= 0 {
+		a.ScaleUpCooldown = Duration(time.Minute)
+	}
+	if a.ScaleDownCooldown <= 0 {
+		a.ScaleDownCooldown = Duration(5 * time.Minute)
+	}
+	return a
+}
+
+// ReplicaNames returns the container names of the project's running replicas
+func (s *Service) ReplicaNames(ctx context.Context, project *models.Record, spec Spec) ([]string, error) {
+	live, err := s.liveReplicas(ctx, project, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, rep := range live {
+		if rep.Target != "" {
+			names = append(names, rep.Name)
+		}
+	}
+	return names, nil
+}
+
+// liveReplicas lists the project's serving containers, ordered by replica index.
+// Containers from before replicas existed (senvanda-app-<name>) count as replica 1.
+func (s *Service) liveReplicas(ctx context.Context, project *models.Record, spec Spec) ([]replica, error) {
+	containers, err := s.dockerClient.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the port Caddy currently dials, the project port may have changed since
+	dials := make(map[string]string)
+	if upstreams, err := s.caddyClient.Upstreams(spec.Domain); err == nil {
+		for _, u := range upstreams {
+			if host, _, ok := strings.Cut(u.Dial, ":"); ok {
+				dials[host] = u.Dial
+			}
+		}
+	}
+
+	var replicas []replica
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		if strings.HasSuffix(name, "-next") || IsTask(c.Labels) {
+			continue // Candidate of an interrupted rollout, or a cron/release container
+		}
+		if c.Labels["senvanda.project_id"] != project.Id && !isLegacyContainer(c.Labels, name, spec.Name) {
+			continue
+		}
+
+		index, err := strconv.Atoi(c.Labels["senvanda.replica"])
+		if err != nil || index < 1 {
+			index = 1
+		}
+
+		rep := replica{Index: index, Name: name, ID: c.ID}
+		if c.State == "running" && c.NetworkSettings != nil {
+			if endpoint, ok := c.NetworkSettings.Networks[NetworkName]; ok && endpoint.IPAddress != "" {
+				rep.IP = endpoint.IPAddress
+				rep.Target = fmt.Sprintf("%s:%d", endpoint.IPAddress, spec.Port)
+				if dial, ok := dials[endpoint.IPAddress]; ok {
+					rep.Target = dial
+				}
+			}
+		}
+		replicas = append(replicas, rep)
+	}
+
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Index < replicas[j].Index })
+	return replicas, nil
+}
+
+// isLegacyContainer reports whether a container is the single, unlabelled container a project
+// had before replicas existed. Labelled containers never match by name: replica 2 of
+// "shop" (senvanda-app-shop-2) is also ContainerName("shop-2").
+func isLegacyContainer(labels map[string]string, name string, projectName string) bool {
+	return labels["senvanda.project_id"] == "" && name == ContainerName(projectName)
+}
+
+func liveTargets(replicas []replica) []string {
+	var targets []string
+	for _, rep := range replicas {
+		if rep.Target != "" {
+			targets = append(targets, rep.Target)
+		}
+	}
+	return targets
+}
+
+// upstreams returns the set Caddy should route to right now: replicas promoted in this
+// release, the candidate being rolled in, and old replicas that haven't been replaced yet.
+// limit > 0 drops old replicas above the desired count.
+func (r *Release) upstreams(limit int) []string {
+	replaced := make(map[int]bool)
+	var targets []string
+	for _, rep := range r.Promoted {
+		replaced[rep.Index] = true
+		targets = append(targets, rep.Target)
+	}
+	if r.candidate > 0 {
+		replaced[r.candidate] = true
+		targets = append(targets, r.Target)
+	}
+	for _, old := range r.Live {
+		if replaced[old.Index] || old.Target == "" || (limit > 0 && old.Index > limit) {
+			continue
+		}
+		targets = append(targets, old.Target)
+	}
+	return targets
+}
+
+// routeTo points the project's domain at targets, load balanced across replicas
+func (s *Service) routeTo(r *Release, targets []string) error {
+	upstreams := make([]caddy.Upstream, 0, len(targets))
+	for _, t := range targets {
+		upstreams = append(upstreams, caddy.Upstream{Dial: t})
+	}
+	if err := s.caddyClient.SetUpstreams(r.Spec.Domain, upstreams, r.Spec.LoadBalancing); err != nil {
+		return fmt.Errorf("failed to configure Caddy: %w", err)
+	}
+	return nil
+}
+
+// startReplica runs the candidate container for replica `index` next to the live one
+func (s *Service) startReplica(ctx context.Context, r *Release, index int) error {
+	r.NextName = ReplicaName(r.Spec.Name, index) + "-next"
+	r.candidate = index
+	log.Printf("▶️ Starting new container as %s...", r.NextName)
+	_ = s.dockerClient.RemoveContainer(ctx, r.NextName) // Leftover from an aborted rollout
+
+	labels := map[string]string{
+		"senvanda.project":    r.Spec.Name,
+		"senvanda.project_id": r.Project.Id,
+		"senvanda.replica":    strconv.Itoa(index),
+	}
+	if r.Deployment != nil {
+		labels["senvanda.deployment"] = r.Deployment.Id
+	}
+
+	env, err := s.containerEnv(r.Spec)
+	if err != nil {
+		return err
+	}
+	ip, err := s.dockerClient.RunContainer(ctx, docker.RunOptions{
+		Name:     r.NextName,
+		Image:    r.Image,
+		Network:  NetworkName,
+		Env:      env,
+		Binds:    r.Spec.Binds,
+		Labels:   labels,
+		CPU:      r.Spec.CPU,
+		MemoryMB: r.Spec.MemoryMB,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.ContainerIP = ip
+	r.Target = fmt.Sprintf("%s:%d", ip, r.Spec.Port)
+	log.Printf("✅ Container started at %s", ip)
+	return nil
+}
+
+// promoteReplica retires the old container of the candidate's index and gives the
+// candidate its final name. The candidate must already be receiving traffic.
+func (s *Service) promoteReplica(ctx context.Context, r *Release) {
+	index := r.candidate
+	for _, old := range r.Live {
+		if old.Index == index {
+			log.Printf("♻️ Removing old container: %s", old.Name)
+			_ = s.dockerClient.RemoveContainer(ctx, old.Name)
+		}
+	}
+
+	name := ReplicaName(r.Spec.Name, index)
+	_ = s.dockerClient.RemoveContainer(ctx, name) // Stopped leftover not listed as live
+	if err := s.dockerClient.RenameContainer(ctx, r.NextName, name); err != nil {
+		// Traffic already flows to the new container, so this is not fatal
+		log.Printf("⚠️ Failed to rename %s to %s: %v", r.NextName, name, err)
+		name = r.NextName
+	}
+
+	promoted := replica{Index: index, Name: name, IP: r.ContainerIP, Target: r.Target}
+	if inspect, err := s.dockerClient.InspectContainer(ctx, name); err == nil {
+		promoted.ID = inspect.ID
+	}
+	r.Promoted = append(r.Promoted, promoted)
+	r.NextName, r.candidate = "", 0
+}
+
+// rollReplica starts, verifies, routes and promotes one replica
+func (s *Service) rollReplica(ctx context.Context, r *Release, index int, limit int) error {
+	if err := s.startReplica(ctx, r, index); err != nil {
+		return err
+	}
+	if err := s.waitForReady(ctx, r.Spec.HealthCheck, r.NextName, r.Target, r.probeReporter(index)); err != nil {
+		r.detail = s.diagnose(ctx, r.NextName)
+		return err
+	}
+	if err := s.routeTo(r, r.upstreams(limit)); err != nil {
+		return err
+	}
+	s.promoteReplica(ctx, r)
+
+	r.Events.Emit(events.TypeReplicaReady, "rollout", fmt.Sprintf("Replica %d/%d ready", index, r.Spec.Replicas), map[string]interface{}{
+		"replica":  index,
+		"replicas": r.Spec.Replicas,
+		"upstream": r.Promoted[len(r.Promoted)-1].Target,
+	})
+	return nil
+}
+
+// retireExtras removes old replicas above the desired count (already out of the route)
+func (s *Service) retireExtras(ctx context.Context, r *Release) {
+	for _, old := range r.Live {
+		if old.Index > r.Spec.Replicas {
+			log.Printf("♻️ Removing surplus replica: %s", old.Name)
+			_ = s.dockerClient.RemoveContainer(ctx, old.Name)
+		}
+	}
+}
+
+// stepRollout promotes replica 1 (already verified and routed) and then replaces
+// the remaining replicas one at a time, so capacity never drops below N-1.
+// A failure halts the rollout; replicas replaced so far keep serving the new release.
+func (s *Service) stepRollout(ctx context.Context, r *Release) error {
+	s.promoteReplica(ctx, r)
+
+	for i := 2; i <= r.Spec.Replicas; i++ {
+		s.setAction(r.Project, fmt.Sprintf("🔁 Rolling out replica %d/%d...", i, r.Spec.Replicas))
+		if err := s.rollReplica(ctx, r, i, 0); err != nil {
+			return fmt.Errorf("replica %d/%d: %w (%d of %d replicas updated)", i, r.Spec.Replicas, err, len(r.Promoted), r.Spec.Replicas)
+		}
+	}
+
+	if err := s.routeTo(r, r.upstreams(r.Spec.Replicas)); err != nil {
+		return err
+	}
+	s.retireExtras(ctx, r)
+	return nil
+}
+
+// Scale changes the replica count of the running release without a redeploy:
+// new replicas run the image of the current ones, surplus replicas are drained first.
+func (s *Service) Scale(ctx context.Context, project *models.Record, replicas int) error {
+	if replicas < 1 || replicas > MaxReplicas {
+		return fmt.Errorf("replicas must be between 1 and %d", MaxReplicas)
+	}
+
+	spec, err := SpecFromProject(project)
+	if err != nil {
+		return err
+	}
+	spec.Replicas = replicas
+
+	live, err := s.liveReplicas(ctx, project, spec)
+	if err != nil {
+		return err
+	}
+	running := liveTargets(live)
+	if len(running) == 0 {
+		return fmt.Errorf("project has no running replicas, deploy it first")
+	}
+	// Scaling down only frees resources, so it is allowed even over a lowered quota
+	if replicas > len(running) {
+		if err := s.Admit(project, spec); err != nil {
+			return err
+		}
+	}
+
+	inspect, err := s.dockerClient.InspectContainer(ctx, live[0].Name)
+	if err != nil {
+		return err
+	}
+
+	r := &Release{
+		Project: project,
+		Spec:    spec,
+		Events:  s.events.For(project.Id, ""),
+		Image:   inspect.Config.Image,
+		Live:    live,
+	}
+
+	log.Printf("📐 Scaling %s from %d to %d replicas", spec.Name, len(running), replicas)
+	have := make(map[int]bool)
+	for _, rep := range live {
+		if rep.Target != "" {
+			have[rep.Index] = true
+		}
+	}
+
+	for i := 1; i <= replicas; i++ {
+		if have[i] {
+			continue
+		}
+		s.setAction(project, fmt.Sprintf("📐 Starting replica %d/%d...", i, replicas))
+		if err := s.rollReplica(ctx, r, i, replicas); err != nil {
+			if r.NextName != "" {
+				_ = s.dockerClient.RemoveContainer(ctx, r.NextName)
+			}
+			s.setAction(project, "")
+			return fmt.Errorf("failed to start replica %d: %w", i, err)
+		}
+	}
+
+	// Drain before removing, so no request hits a container that is going away
+	if err := s.routeTo(r, r.upstreams(replicas)); err != nil {
+		return err
+	}
+	s.retireExtras(ctx, r)
+
+	r.Events.Emit(events.TypeScaled, "scale", fmt.Sprintf("Scaled to %d replicas", replicas), map[string]interface{}{
+		"replicas":  replicas,
+		"upstreams": r.upstreams(replicas),
+	})
+	s.setAction(project, "")
+	return nil
+}
+
+func (s *Service) runScaleJob(ctx context.Context, job *models.Record) error {
+	project, err := s.app.Dao().FindRecordById("projects", job.GetString("project"))
+	if err != nil {
+		return err
+	}
+
+	var params struct {
+		Replicas int `json:"replicas"`
+	}
+	if err := job.UnmarshalJSONField("params", &params); err != nil {
+		return err
+	}
+
+	return s.Scale(ctx, project, params.Replicas)
+}
+
+// SetReplicas stores the desired replica count in the project settings,
+// so later deploys keep the same scale
+func (s *Service) SetReplicas(project *models.Record, replicas int) error {
+	settings := map[string]interface{}{}
+	if raw := project.GetString("settings"); raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return fmt.Errorf("invalid project settings: %w", err)
+		}
+	}
+	settings["replicas"] = replicas
+	project.Set("settings", settings)
+	return s.app.Dao().SaveRecord(project)
+}
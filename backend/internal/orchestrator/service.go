@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/models"
 
+	"github.com/senvanda/backend/internal/events"
+	"github.com/senvanda/backend/internal/history"
 	"github.com/senvanda/backend/internal/infrastructure/caddy"
 	"github.com/senvanda/backend/internal/infrastructure/docker"
 	"github.com/senvanda/backend/internal/infrastructure/woodpecker"
+	"github.com/senvanda/backend/internal/queue"
 )
 
 type Service struct {
@@ -19,17 +22,63 @@ type Service struct {
 	dockerClient     *docker.Client
 	caddyClient      *caddy.Client
 	woodpeckerClient *woodpecker.Client
+	history          *history.Service
+	events           *events.Service
+	jobs             *queue.Service // Set by RegisterJobs
+
+	canaryMu sync.Mutex
+	canaries map[string]*canaryRun // projectID -> in-flight canary
+
+	admission  Admission
+	decryptEnv EnvDecrypter
 }
 
-func NewService(app *pocketbase.PocketBase, dockerClient *docker.Client, caddyClient *caddy.Client, woodpeckerClient *woodpecker.Client) *Service {
+// Admission decides whether a project may run with the given spec (quotas).
+// It returns an error explaining the refusal.
+type Admission func(project *models.Record, spec Spec) error
+
+// EnvDecrypter opens the encrypted (secret) values of KEY=value entries
+type EnvDecrypter func(env []string) ([]string, error)
+
+func NewService(app *pocketbase.PocketBase, dockerClient *docker.Client, caddyClient *caddy.Client, woodpeckerClient *woodpecker.Client, historySvc *history.Service, eventsSvc *events.Service) *Service {
 	return &Service{
 		app:              app,
 		dockerClient:     dockerClient,
 		caddyClient:      caddyClient,
 		woodpeckerClient: woodpeckerClient,
+		history:          historySvc,
+		events:           eventsSvc,
+		canaries:         make(map[string]*canaryRun),
 	}
 }
 
+// SetAdmission installs the check that runs before every deploy and scale
+func (s *Service) SetAdmission(admission Admission) {
+	s.admission = admission
+}
+
+// SetEnvDecrypter installs what decrypts secret env vars for new containers
+func (s *Service) SetEnvDecrypter(decrypt EnvDecrypter) {
+	s.decryptEnv = decrypt
+}
+
+// containerEnv is the env handed to a container: spec env with secrets decrypted.
+// Secrets stay encrypted in the Spec and are only opened here.
+func (s *Service) containerEnv(spec Spec) ([]string, error) {
+	if s.decryptEnv == nil {
+		return spec.Env, nil
+	}
+	return s.decryptEnv(spec.Env)
+}
+
+// Admit runs the admission check, so API handlers can refuse before queueing a job
+func (s *Service) Admit(project *models.Record, spec Spec) error {
+	if s.admission == nil {
+		return nil
+	}
+	return s.admission(project, spec)
+}
+
 // TriggerBuildPipeline initiates a build in Woodpecker CI
 func (s *Service) TriggerBuildPipeline(project *models.Record, branch string) error {
 	repoOwner := project.GetString("repo_owner") // e.g. "melvin"
@@ -65,129 +114,132 @@ func (s *Service) TriggerBuildPipeline(project *models.Record, branch string) er
 	return nil
 }
 
-// DeployUserApp handles the full deployment lifecycle:
-// 1. Prepare (Update DB Status)
-// 2. Docker Action (Pull, Remove Old, Run New)
-// 3. Caddy Action (Route Traffic)
-// 4. Finalize (Update DB Status)
-func (s *Service) DeployUserApp(project *models.Record, imageTag string) error {
-	ctx := context.Background() // Background context for the long-running process
-	projectName := project.GetString("name")
-
-	// Default to project name if no domain specified, assuming local dev environment for now
-	// In production, this would be retrieved from project record
-	domain := fmt.Sprintf("%s.senvanda.local", projectName)
-
-	// Phase 1: Preparation
-	log.Printf("🚀 Starting deployment for %s...", projectName)
-	project.Set("status", "deploying")
-	project.Set("current_action", "🚀 Initializing deployment...")
-	s.app.Dao().SaveRecord(project)
+// RegisterJobs binds the orchestrator's deploy kinds to the deploy queue
+func (s *Service) RegisterJobs(q *queue.Service) {
+	s.jobs = q
+	q.Register(queue.KindDeploy, s.runDeployJob)
+	q.Register(queue.KindRedeploy, s.runRedeployJob)
+	q.Register(queue.KindRollback, s.runRollbackJob)
+	q.Register(queue.KindScale, s.runScaleJob)
+	q.Register(queue.KindTeardown, s.runTeardownJob)
+}
 
-	// Phase 2: Docker Action
-	containerName := fmt.Sprintf("senvanda-app-%s", projectName)
-	networkName := "senvanda-apps" // Dedicated isolated network
+// DeployJobParams are the params of a queued "deploy" job
+type DeployJobParams struct {
+	Image       string `json:"image"`
+	CommitSHA   string `json:"commit_sha"`
+	BuildNumber int    `json:"build_number"`
+}
 
-	// A. Pull Image
-	log.Printf("📦 Pulling image: %s", imageTag)
-	project.Set("current_action", "📦 Pulling latest docker image...")
-	s.app.Dao().SaveRecord(project)
-	
-	if err := s.dockerClient.PullImage(ctx, imageTag); err != nil {
-		s.markFailed(project, fmt.Sprintf("Failed to pull image: %v", err))
+func (s *Service) runDeployJob(ctx context.Context, job *models.Record) error {
+	project, err := s.app.Dao().FindRecordById("projects", job.GetString("project"))
+	if err != nil {
 		return err
 	}
 
-	// B. Remove Old Container
-	log.Printf("♻️ Removing old container: %s", containerName)
-	project.Set("current_action", "♻️ Rotating containers...")
-	s.app.Dao().SaveRecord(project)
-	_ = s.dockerClient.RemoveContainer(ctx, containerName) // Ignore error if not exists
+	var params DeployJobParams
+	if err := job.UnmarshalJSONField("params", &params); err != nil {
+		return err
+	}
 
-	// C. Run New Container
-	log.Printf("▶️ Starting new container...")
-	project.Set("current_action", "▶️ Starting new container...")
-	s.app.Dao().SaveRecord(project)
+	return s.DeployUserApp(project, params.Image, history.Meta{
+		Trigger:     job.GetString("trigger"),
+		CommitSHA:   params.CommitSHA,
+		BuildNumber: params.BuildNumber,
+		JobID:       job.Id,
+	})
+}
 
-	// Extract Volumes from DB
-	var binds []string
-	if volumesData := project.Get("volumes"); volumesData != nil {
-		if volList, ok := volumesData.([]interface{}); ok {
-			for _, v := range volList {
-				if vm, ok := v.(map[string]interface{}); ok {
-					host := fmt.Sprintf("%v", vm["host"])
-					container := fmt.Sprintf("%v", vm["container"])
-					if host != "" && container != "" {
-						binds = append(binds, fmt.Sprintf("%s:%s", host, container))
-					}
-				} else if vs, ok := v.(string); ok {
-					binds = append(binds, vs)
-				}
-			}
-		}
+func (s *Service) runRedeployJob(ctx context.Context, job *models.Record) error {
+	project, err := s.app.Dao().FindRecordById("projects", job.GetString("project"))
+	if err != nil {
+		return err
 	}
 
-	// Extract Resources from Settings
-	var cpu float64 = 0.5  // Default
-	var memory int64 = 512 // Default (MB)
-
-	if settingsData := project.Get("settings"); settingsData != nil {
-		if settings, ok := settingsData.(map[string]interface{}); ok {
-			if resources, ok := settings["resources"].(map[string]interface{}); ok {
-				// Parse CPU
-				if cpuVal, ok := resources["cpu"].(string); ok {
-					fmt.Sscanf(cpuVal, "%f", &cpu)
-				} else if cpuVal, ok := resources["cpu"].(float64); ok {
-					cpu = cpuVal
-				}
-
-				// Parse Memory (expecting "512MB" or 512)
-				if memVal, ok := resources["memory"].(string); ok {
-					fmt.Sscanf(memVal, "%d", &memory)
-				} else if memVal, ok := resources["memory"].(float64); ok {
-					memory = int64(memVal)
-				}
-			}
-		}
-	}
+	return s.Redeploy(project, history.Meta{
+		Trigger: job.GetString("trigger"),
+		JobID:   job.Id,
+	})
+}
 
-	containerIP, err := s.dockerClient.RunContainer(ctx, containerName, imageTag, networkName, binds, cpu, memory)
+func (s *Service) runRollbackJob(ctx context.Context, job *models.Record) error {
+	project, err := s.app.Dao().FindRecordById("projects", job.GetString("project"))
 	if err != nil {
-		s.markFailed(project, fmt.Sprintf("Failed to start container: %v", err))
 		return err
 	}
-	log.Printf("✅ Container started at %s", containerIP)
 
-	// Phase 3: Caddy Action
-	// Retrieve port from DB, default to 80
-	appPort := project.GetInt("port")
-	if appPort == 0 {
-		appPort = 80
+	var params struct {
+		DeploymentID string `json:"deployment_id"`
+	}
+	if err := job.UnmarshalJSONField("params", &params); err != nil {
+		return err
 	}
-	target := fmt.Sprintf("%s:%d", containerIP, appPort)
 
-	log.Printf("📡 Configuring Caddy route for %s -> %s", domain, target)
-	project.Set("current_action", "📡 Configuring secure proxy...")
-	s.app.Dao().SaveRecord(project)
+	return s.Rollback(project, params.DeploymentID, job.Id)
+}
+
+// DeployUserApp deploys a registry image (Woodpecker /deploy-final) through the pipeline
+func (s *Service) DeployUserApp(project *models.Record, imageTag string, meta history.Meta) error {
+	return s.Deploy(project, Request{Image: imageTag, Meta: meta})
+}
 
-	if err := s.caddyClient.AddLinkDomain(domain, target); err != nil {
-		s.markFailed(project, fmt.Sprintf("Failed to configure Caddy: %v", err))
+// Redeploy deploys the project from its own configuration: built from source for
+// "custom-build" projects, otherwise the configured image.
+func (s *Service) Redeploy(project *models.Record, meta history.Meta) error {
+	return s.Deploy(project, Request{Meta: meta})
+}
+
+// Rollback redeploys the exact image (by digest) and settings snapshot of an earlier
+// successful deployment. deploymentID is optional; empty means "the release before the current one".
+// The new deployment is recorded with the "rollback" trigger. When it fails, the project
+// gets its current settings, volumes and port back: the newer release keeps serving.
+func (s *Service) Rollback(project *models.Record, deploymentID string, jobID string) error {
+	target, err := s.history.FindRollbackTarget(project.Id, deploymentID)
+	if err != nil {
 		return err
 	}
 
-	// Phase 4: Finalize
-	project.Set("status", "online")
-	project.Set("last_deployed", time.Now())
-	project.Set("internal_ip", containerIP)
-	project.Set("url", fmt.Sprintf("http://%s", domain))
-	project.Set("current_action", "") // Clear action on success
-
-	if err := s.app.Dao().SaveRecord(project); err != nil {
-		log.Printf("⚠️ Failed to save final project state: %v", err)
+	imageRef := s.resolveRollbackImage(target)
+	settings, volumes, port := project.Get("settings"), project.Get("volumes"), project.Get("port")
+	history.RestoreSnapshot(project, target)
+
+	log.Printf("⏪ Rolling back %s to deployment %s (%s)", project.GetString("name"), target.Id, imageRef)
+
+	err = s.Deploy(project, Request{
+		Image: imageRef,
+		Meta: history.Meta{
+			Trigger:     history.TriggerRollback,
+			CommitSHA:   target.GetString("commit_sha"),
+			BuildNumber: target.GetInt("build_number"),
+			RollbackOf:  target.Id,
+			JobID:       jobID,
+		},
+	})
+	if err != nil {
+		project.Set("settings", settings)
+		project.Set("volumes", volumes)
+		project.Set("port", port)
+		if saveErr := s.app.Dao().SaveRecord(project); saveErr != nil {
+			log.Printf("⚠️ Failed to restore the configuration of %s after the failed rollback: %v", project.GetString("name"), saveErr)
+		}
 	}
+	return err
+}
 
-	log.Printf("🎉 Deployment for %s completed successfully!", projectName)
-	return nil
+// resolveRollbackImage prefers repo@digest, but falls back to the bare image ID
+// for images that only exist locally (no registry digest).
+func (s *Service) resolveRollbackImage(target *models.Record) string {
+	ctx := context.Background()
+	pinned := history.PinnedImage(target)
+	digest := target.GetString("image_digest")
+
+	if _, err := s.dockerClient.ImageDigest(ctx, pinned); err == nil || digest == "" {
+		return pinned
+	}
+	if _, err := s.dockerClient.ImageDigest(ctx, digest); err == nil {
+		return digest
+	}
+	return pinned
 }
 
 func (s *Service) markFailed(project *models.Record, reason string) {
@@ -0,0 +1,299 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/infrastructure/caddy"
+)
+
+const (
+	// NetworkName is the dedicated isolated network every project container joins
+	NetworkName = "senvanda-apps"
+
+	// CustomBuildImage marks projects that are built from their repository's Dockerfile
+	CustomBuildImage = "custom-build"
+
+	defaultImage    = "nginx:alpine"
+	defaultPort     = 80
+	defaultCPU      = 0.5
+	defaultMemoryMB = 512
+	defaultPolicy   = caddy.PolicyRoundRobin
+
+	defaultLogMaxAge   = 7 * 24 * time.Hour
+	defaultLogMaxLines = 100000
+
+	// MaxReplicas caps the replica count of a single project
+	MaxReplicas = 10
+)
+
+// ContainerName returns the canonical container name of a project
+func ContainerName(projectName string) string {
+	return fmt.Sprintf("senvanda-app-%s", projectName)
+}
+
+// ReplicaName returns the container name of replica i (1-based) of a project
+func ReplicaName(projectName string, i int) string {
+	return fmt.Sprintf("%s-%d", ContainerName(projectName), i)
+}
+
+// LegacyContainerName is the name used by the old `docker build` engine
+func LegacyContainerName(projectName string) string {
+	return "senvanda-" + projectName
+}
+
+// DefaultDomain is the local domain a project is served on when none is configured
+func DefaultDomain(projectName string) string {
+	return fmt.Sprintf("%s.senvanda.local", projectName)
+}
+
+// Spec is the single parsed view of a project's deploy configuration.
+// Every entry point goes through SpecFromProject so env, resources and volumes
+// are interpreted the same way regardless of how the deploy was triggered.
+type Spec struct {
+	Name        string
+	Image       string // Configured image, or CustomBuildImage
+	RepoURL     string
+	Branch      string
+	Port        int
+	Domain      string
+	Env         []string // KEY=value; secret values stay encrypted until containerEnv
+	Binds       []string // host:container
+	CPU         float64  // Cores
+	MemoryMB    int64
+	HealthCheck *HealthCheck
+	Canary      *Canary // nil = switch all traffic at once
+
+	Replicas      int        // Number of containers behind the domain
+	LoadBalancing string     // Caddy selection policy across replicas
+	Autoscale     *Autoscale // nil = fixed replica count
+	Previews      *Previews  // nil = pushes to other branches are ignored
+
+	CronJobs       []CronJob // Scheduled one-off commands
+	ReleaseCommand string    // Run from the new image before it takes traffic (migrations)
+
+	LogRetention LogRetention // How long collected container logs are kept
+}
+
+// LogRetention is the `logRetention` block of the project settings, e.g. {"maxAge": "72h", "maxLines": 50000}.
+// Collected lines older than MaxAge, or beyond the newest MaxLines, are deleted.
+type LogRetention struct {
+	MaxAge   Duration `json:"maxAge"`
+	MaxLines int      `json:"maxLines"`
+}
+
+// BuildsFromSource reports whether the image has to be built from the repository
+func (s Spec) BuildsFromSource() bool {
+	return s.Image == CustomBuildImage && s.RepoURL != ""
+}
+
+type settingsDoc struct {
+	Branch  string `json:"branch"`
+	Domain  string `json:"domain"`
+	EnvVars []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"` // Numbers and booleans are accepted too
+	} `json:"envVars"`
+	Resources struct {
+		CPU    interface{} `json:"cpu"`
+		Memory interface{} `json:"memory"`
+	} `json:"resources"`
+	HealthCheck *HealthCheck `json:"healthCheck"`
+	Canary      *Canary      `json:"canary"`
+
+	Replicas      int        `json:"replicas"`
+	LoadBalancing string     `json:"loadBalancing"`
+	Autoscale     *Autoscale `json:"autoscale"`
+	Previews      *Previews  `json:"previews"`
+
+	CronJobs       []CronJob `json:"cronJobs"`
+	ReleaseCommand string    `json:"releaseCommand"`
+
+	LogRetention *LogRetention `json:"logRetention"`
+}
+
+// SpecFromProject parses the project record (settings, volumes, port, image) into a Spec
+func SpecFromProject(project *models.Record) (Spec, error) {
+	name := project.GetString("name")
+	spec := Spec{
+		Name:     name,
+		Image:    project.GetString("image"),
+		RepoURL:  project.GetString("repoUrl"),
+		Branch:   "main",
+		Port:     project.GetInt("port"),
+		Domain:   DefaultDomain(name),
+		CPU:      defaultCPU,
+		MemoryMB: defaultMemoryMB,
+
+		Replicas:      1,
+		LoadBalancing: defaultPolicy,
+
+		LogRetention: LogRetention{
+			MaxAge:   Duration(defaultLogMaxAge),
+			MaxLines: defaultLogMaxLines,
+		},
+	}
+	if spec.Port == 0 {
+		spec.Port = defaultPort
+	}
+
+	var settings settingsDoc
+	if raw := project.GetString("settings"); raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return spec, fmt.Errorf("invalid project settings: %w", err)
+		}
+	}
+
+	if settings.Branch != "" {
+		spec.Branch = settings.Branch
+	}
+	if settings.Domain != "" {
+		spec.Domain = settings.Domain
+	}
+	for _, e := range settings.EnvVars {
+		if e.Key == "" {
+			continue
+		}
+		value, err := EnvValue(e.Value)
+		if err != nil {
+			return spec, fmt.Errorf("invalid project settings: env var %s: %w", e.Key, err)
+		}
+		spec.Env = append(spec.Env, e.Key+"="+value)
+	}
+	if cpu, ok := parseCPU(settings.Resources.CPU); ok {
+		spec.CPU = cpu
+	}
+	if mem, ok := ParseMemoryMB(settings.Resources.Memory); ok {
+		spec.MemoryMB = mem
+	}
+	if settings.HealthCheck != nil {
+		hc := settings.HealthCheck.withDefaults()
+		spec.HealthCheck = &hc
+	}
+	if settings.Canary != nil && settings.Canary.Enabled {
+		canary := settings.Canary.withDefaults()
+		spec.Canary = &canary
+	}
+	if settings.Replicas > 0 {
+		spec.Replicas = min(settings.Replicas, MaxReplicas)
+	}
+	if settings.Autoscale != nil && settings.Autoscale.Enabled {
+		autoscale := settings.Autoscale.withDefaults()
+		spec.Autoscale = &autoscale
+		spec.Replicas = min(max(spec.Replicas, autoscale.MinReplicas), autoscale.MaxReplicas)
+	}
+	if settings.Previews != nil && settings.Previews.Enabled {
+		previews := settings.Previews.withDefaults()
+		spec.Previews = &previews
+	}
+	for i, job := range settings.CronJobs {
+		if job.Command != "" {
+			spec.CronJobs = append(spec.CronJobs, job.withDefaults(i))
+		}
+	}
+	spec.ReleaseCommand = strings.TrimSpace(settings.ReleaseCommand)
+	if settings.LogRetention != nil {
+		if settings.LogRetention.MaxAge > 0 {
+			spec.LogRetention.MaxAge = settings.LogRetention.MaxAge
+		}
+		if settings.LogRetention.MaxLines > 0 {
+			spec.LogRetention.MaxLines = settings.LogRetention.MaxLines
+		}
+	}
+	switch settings.LoadBalancing {
+	case caddy.PolicyRoundRobin, caddy.PolicyLeastConn, caddy.PolicyRandom, caddy.PolicyIPHash:
+		spec.LoadBalancing = settings.LoadBalancing
+	}
+
+	binds, err := parseVolumes(project.GetString("volumes"))
+	if err != nil {
+		return spec, err
+	}
+	spec.Binds = binds
+
+	return spec, nil
+}
+
+// EnvValue formats an env var value from the project settings: strings as-is, numbers
+// without an exponent (3000, not 3e+03), booleans as true/false. Objects and arrays are refused.
+func EnvValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	default:
+		return "", errors.New("value must be a string, number or boolean")
+	}
+}
+
+// parseVolumes accepts [{"host": "...", "container": "..."}] or ["host:container"]
+func parseVolumes(raw string) ([]string, error) {
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+
+	var items []interface{}
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, fmt.Errorf("invalid project volumes: %w", err)
+	}
+
+	var binds []string
+	for _, v := range items {
+		switch vol := v.(type) {
+		case map[string]interface{}:
+			host, _ := vol["host"].(string)
+			target, _ := vol["container"].(string)
+			if host != "" && target != "" {
+				binds = append(binds, fmt.Sprintf("%s:%s", host, target))
+			}
+		case string:
+			if vol != "" {
+				binds = append(binds, vol)
+			}
+		}
+	}
+	return binds, nil
+}
+
+func parseCPU(v interface{}) (float64, bool) {
+	switch cpu := v.(type) {
+	case float64:
+		return cpu, cpu > 0
+	case string:
+		val, err := strconv.ParseFloat(strings.TrimSpace(cpu), 64)
+		return val, err == nil && val > 0
+	}
+	return 0, false
+}
+
+// ParseMemoryMB accepts 512, "512", "512MB" or "1GB" and returns megabytes
+func ParseMemoryMB(v interface{}) (int64, bool) {
+	switch mem := v.(type) {
+	case float64:
+		return int64(mem), mem > 0
+	case string:
+		m := strings.ToUpper(strings.TrimSpace(mem))
+		multiplier := int64(1)
+		if strings.HasSuffix(m, "GB") || strings.HasSuffix(m, "G") {
+			multiplier = 1024
+		}
+		m = strings.TrimRight(m, "GMB")
+		val, err := strconv.ParseFloat(strings.TrimSpace(m), 64)
+		if err != nil || val <= 0 {
+			return 0, false
+		}
+		return int64(val * float64(multiplier)), true
+	}
+	return 0, false
+}
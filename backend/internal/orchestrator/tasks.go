@@ -0,0 +1,149 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+)
+
+// TaskLabel marks one-off containers (value = task kind). They share the project
+// label with the replicas but never serve traffic.
+const TaskLabel = "senvanda.task"
+
+// TaskLabel values
+const (
+	TaskCron    = "cron"    // Scheduled job
+	TaskRelease = "release" // Release command of a deployment
+)
+
+const (
+	defaultCronTimeout = 10 * time.Minute
+	releaseTimeout     = 15 * time.Minute
+)
+
+// CronJob is one entry of the `cronJobs` list in the project settings, e.g.
+// {"name": "cleanup", "schedule": "0 3 * * *", "command": "php artisan cleanup", "timeout": "5m"}
+type CronJob struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"` // 5-field cron expression, evaluated every minute
+	Command  string   `json:"command"`  // Run with /bin/sh -c
+	Timeout  Duration `json:"timeout"`
+}
+
+func (j CronJob) withDefaults(i int) CronJob {
+	j.Name = BranchSlug(j.Name)
+	if j.Name == "" {
+		j.Name = fmt.Sprintf("job-%d", i+1)
+	}
+	j.Schedule = strings.TrimSpace(j.Schedule)
+	if j.Timeout <= 0 {
+		j.Timeout = Duration(defaultCronTimeout)
+	}
+	return j
+}
+
+// IsTask reports whether a container is a one-off task rather than a replica
+func IsTask(labels map[string]string) bool {
+	return labels[TaskLabel] != ""
+}
+
+// CurrentImage returns the image the project's live replicas run
+func (s *Service) CurrentImage(ctx context.Context, project *models.Record, spec Spec) (string, error) {
+	names, err := s.ReplicaNames(ctx, project, spec)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("project has no running replicas, deploy it first")
+	}
+
+	inspect, err := s.dockerClient.InspectContainer(ctx, names[0])
+	if err != nil {
+		return "", err
+	}
+	return inspect.Config.Image, nil
+}
+
+// RunCronJob runs a scheduled job once, in a one-off container from the project's
+// current image with the same env, volumes and resource limits as the replicas
+func (s *Service) RunCronJob(ctx context.Context, project *models.Record, job CronJob) (docker.TaskResult, string, error) {
+	spec, err := SpecFromProject(project)
+	if err != nil {
+		return docker.TaskResult{}, "", err
+	}
+	image, err := s.CurrentImage(ctx, project, spec)
+	if err != nil {
+		return docker.TaskResult{}, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(job.Timeout))
+	defer cancel()
+
+	result, err := s.runTask(ctx, project, spec, image, TaskCron, job.Name, job.Command)
+	return result, image, err
+}
+
+func hasReleaseCommand(r *Release) bool {
+	return r.Spec.ReleaseCommand != ""
+}
+
+// stepRelease runs the release command (e.g. migrations) from the new image with the
+// project's env and network. A non-zero exit aborts the deploy before any container
+// of the new release starts.
+func (s *Service) stepRelease(ctx context.Context, r *Release) error {
+	ctx, cancel := context.WithTimeout(ctx, releaseTimeout)
+	defer cancel()
+
+	result, err := s.runTask(ctx, r.Project, r.Spec, r.Image, TaskRelease, "", r.Spec.ReleaseCommand)
+	if err != nil {
+		return fmt.Errorf("failed to run release command: %w", err)
+	}
+	s.history.SetReleaseOutput(r.Deployment, result.Output, result.ExitCode)
+
+	switch {
+	case result.TimedOut:
+		r.detail = lastLines(result.Output, diagnosticLogLines)
+		return fmt.Errorf("release command timed out after %s", releaseTimeout)
+	case result.ExitCode != 0:
+		r.detail = lastLines(result.Output, diagnosticLogLines)
+		return fmt.Errorf("release command exited with code %d", result.ExitCode)
+	}
+
+	log.Printf("✅ Release command for %s finished", r.Spec.Name)
+	return nil
+}
+
+// runTask runs a command in a throwaway container named senvanda-app-<name>-<kind>[-<task>]-<unix>
+func (s *Service) runTask(ctx context.Context, project *models.Record, spec Spec, image string, kind string, name string, command string) (docker.TaskResult, error) {
+	prefix := fmt.Sprintf("%s-%s", ContainerName(spec.Name), kind)
+	if name != "" {
+		prefix += "-" + name
+	}
+	containerName := fmt.Sprintf("%s-%d", prefix, time.Now().Unix())
+	log.Printf("⏱️ Running %s task %s: %s", kind, containerName, command)
+
+	env, err := s.containerEnv(spec)
+	if err != nil {
+		return docker.TaskResult{}, err
+	}
+	return s.dockerClient.RunTask(ctx, docker.RunOptions{
+		Name:    containerName,
+		Image:   image,
+		Network: NetworkName,
+		Env:     env,
+		Binds:   spec.Binds,
+		Labels: map[string]string{
+			"senvanda.project":    spec.Name,
+			"senvanda.project_id": project.Id,
+			TaskLabel:             kind,
+		},
+		CPU:      spec.CPU,
+		MemoryMB: spec.MemoryMB,
+	}, command)
+}
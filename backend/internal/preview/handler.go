@@ -0,0 +1,74 @@
+package preview
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/queue"
+)
+
+// Handler exposes the preview environments of a project
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new preview handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the preview endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/previews", h.handleListPreviews)
+	g.DELETE("/deploy/:id/previews/:previewId", h.handleDeletePreview)
+}
+
+type previewView struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Branch       string `json:"branch"`
+	URL          string `json:"url"`
+	Status       string `json:"status"`
+	LastActivity string `json:"last_activity"`
+}
+
+func (h *Handler) handleListPreviews(c echo.Context) error {
+	records, err := h.service.List(c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Failed to list previews", err)
+	}
+
+	items := make([]previewView, 0, len(records))
+	for _, r := range records {
+		items = append(items, previewView{
+			ID:           r.Id,
+			Name:         r.GetString("name"),
+			Branch:       r.GetString("preview_branch"),
+			URL:          "http://" + orchestrator.DefaultDomain(r.GetString("name")),
+			Status:       r.GetString("status"),
+			LastActivity: r.GetString("last_activity"),
+		})
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleDeletePreview tears a preview down before its branch is deleted or it expires
+// URL: DELETE /api/senvanda/deploy/:id/previews/:previewId
+func (h *Handler) handleDeletePreview(c echo.Context) error {
+	preview, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("previewId"))
+	if err != nil || preview.GetString("preview_of") != c.PathParam("id") {
+		return apis.NewNotFoundError("Preview not found", err)
+	}
+
+	job, err := h.service.jobs.Enqueue(preview.Id, queue.KindTeardown, history.TriggerManual, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to queue teardown"})
+	}
+	audit.Queued(c, job.Id)
+	return c.JSON(http.StatusAccepted, queue.JobResponse(job, "Preview teardown queued."))
+}
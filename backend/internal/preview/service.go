@@ -0,0 +1,205 @@
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/senvanda/backend/internal/history"
+	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/queue"
+)
+
+// CategoryPreview marks child projects created for a branch
+const CategoryPreview = "preview"
+
+// TriggerExpired is the job trigger of teardowns started by the TTL janitor
+const TriggerExpired = "expired"
+
+// Settings a preview must not inherit from its parent (a branch must not run
+// migrations or scheduled jobs against the parent's data)
+var productionOnly = []string{"domain", "replicas", "autoscale", "canary", "previews", "cronJobs", "releaseCommand"}
+
+// Service manages per-branch preview environments: child projects that inherit the
+// parent's settings and are deployed through the regular queue and pipeline
+type Service struct {
+	app  core.App
+	jobs *queue.Service
+}
+
+// NewService creates a new preview service
+func NewService(app core.App, jobs *queue.Service) *Service {
+	return &Service{app: app, jobs: jobs}
+}
+
+// Deploy creates (or refreshes) the preview of a branch and queues its deployment.
+// Returns the preview project and the queued job.
+func (s *Service) Deploy(parent *models.Record, branch string) (*models.Record, *models.Record, error) {
+	preview, err := s.ensure(parent, branch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job, err := s.jobs.Enqueue(preview.Id, queue.KindRedeploy, history.TriggerWebhook, nil)
+	if err != nil {
+		return preview, nil, err
+	}
+	return preview, job, nil
+}
+
+// Destroy queues the teardown of a branch preview (nil job when there is none)
+func (s *Service) Destroy(parent *models.Record, branch string) (*models.Record, error) {
+	preview, err := s.find(parent.Id, branch)
+	if err != nil {
+		return nil, nil
+	}
+	return s.jobs.Enqueue(preview.Id, queue.KindTeardown, history.TriggerWebhook, nil)
+}
+
+// List returns the previews of a project
+func (s *Service) List(parentID string) ([]*models.Record, error) {
+	return s.app.Dao().FindRecordsByFilter(
+		"projects", "preview_of = {:parent}", "-last_activity", 0, 0,
+		dbx.Params{"parent": parentID},
+	)
+}
+
+func (s *Service) find(parentID string, branch string) (*models.Record, error) {
+	return s.app.Dao().FindFirstRecordByFilter(
+		"projects", "preview_of = {:parent} && preview_branch = {:branch}",
+		dbx.Params{"parent": parentID, "branch": branch},
+	)
+}
+
+// ensure creates the preview project on the first push and re-syncs it with the
+// parent on every push, so config changes on the parent reach open previews
+func (s *Service) ensure(parent *models.Record, branch string) (*models.Record, error) {
+	preview, err := s.find(parent.Id, branch)
+	if err != nil {
+		collection, err := s.app.Dao().FindCollectionByNameOrId("projects")
+		if err != nil {
+			return nil, err
+		}
+		name := orchestrator.PreviewName(parent.GetString("name"), branch)
+		if err := s.checkName(name); err != nil {
+			return nil, err
+		}
+		preview = models.NewRecord(collection)
+		preview.Set("name", name)
+		preview.Set("preview_of", parent.Id)
+		preview.Set("preview_branch", branch)
+		preview.Set("category", CategoryPreview)
+		preview.Set("status", "building")
+		log.Printf("🌿 Creating preview %s for branch %s", preview.GetString("name"), branch)
+	}
+
+	settings, err := inheritSettings(parent.GetString("settings"), branch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Volumes are deliberately not inherited: a preview must never write production data
+	for _, field := range []string{"user", "team", "image", "repoUrl", "port", "repo_owner", "repo_name"} {
+		preview.Set(field, parent.Get(field))
+	}
+	preview.Set("settings", settings)
+	preview.Set("last_activity", types.NowDateTime())
+
+	if err := s.app.Dao().SaveRecord(preview); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// checkName refuses a preview name that is already a project, or that would make the
+// preview's containers look like a replica of one (<project>-<n>)
+func (s *Service) checkName(name string) error {
+	taken := []string{name}
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			taken = append(taken, name[:i])
+		}
+	}
+	for _, project := range taken {
+		if _, err := s.app.Dao().FindFirstRecordByData("projects", "name", project); err == nil {
+			return fmt.Errorf("preview name %s collides with project %s", name, project)
+		}
+	}
+	return nil
+}
+
+// inheritSettings copies the parent settings for a preview: same env, resources and
+// health check, but its own branch and domain and a single replica
+func inheritSettings(raw string, branch string) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	if raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return nil, fmt.Errorf("invalid project settings: %w", err)
+		}
+	}
+	for _, key := range productionOnly {
+		delete(settings, key)
+	}
+	settings["branch"] = branch
+	return settings, nil
+}
+
+// StartJanitor periodically tears down previews idle for longer than their TTL
+// and previews whose parent project no longer exists
+func (s *Service) StartJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.sweep()
+		}
+	}()
+}
+
+func (s *Service) sweep() {
+	previews, err := s.app.Dao().FindRecordsByFilter("projects", "preview_of != ''", "", 0, 0)
+	if err != nil {
+		return
+	}
+
+	for _, preview := range previews {
+		if s.jobs.HasPending(preview.Id) {
+			continue
+		}
+
+		reason := ""
+		parent, err := s.app.Dao().FindRecordById("projects", preview.GetString("preview_of"))
+		if err != nil {
+			reason = "parent project was deleted"
+		} else {
+			spec, _ := orchestrator.SpecFromProject(parent)
+			ttl := 72 * time.Hour
+			if spec.Previews != nil {
+				ttl = time.Duration(spec.Previews.TTL)
+			}
+			lastActivity := preview.GetDateTime("last_activity").Time()
+			if lastActivity.IsZero() {
+				lastActivity = preview.Created.Time()
+			}
+			if time.Since(lastActivity) > ttl {
+				reason = fmt.Sprintf("no push for %s", ttl)
+			}
+		}
+
+		if reason == "" {
+			continue
+		}
+		log.Printf("⌛ Expiring preview %s: %s", preview.GetString("name"), reason)
+		if _, err := s.jobs.Enqueue(preview.Id, queue.KindTeardown, TriggerExpired, nil); err != nil {
+			log.Printf("⚠️ Failed to queue teardown of %s: %v", preview.GetString("name"), err)
+		}
+	}
+}
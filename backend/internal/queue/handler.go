@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// Handler lets clients poll queued deploy jobs
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new deploy queue handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the job polling endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/jobs/:jobId", h.handleGetJob)
+	g.GET("/deploy/:id/jobs", h.handleListJobs)
+}
+
+type jobView struct {
+	ID           string `json:"id"`
+	Project      string `json:"project"`
+	Kind         string `json:"kind"`
+	Trigger      string `json:"trigger"`
+	Status       string `json:"status"`
+	Error        string `json:"error"`
+	SupersededBy string `json:"superseded_by,omitempty"`
+	DeploymentID string `json:"deployment_id,omitempty"`
+	Position     int    `json:"position,omitempty"` // Queued jobs ahead of this one
+	QueuedAt     string `json:"queued_at"`
+	StartedAt    string `json:"started_at"`
+	FinishedAt   string `json:"finished_at"`
+}
+
+func (h *Handler) toView(job *models.Record) jobView {
+	view := jobView{
+		ID:           job.Id,
+		Project:      job.GetString("project"),
+		Kind:         job.GetString("kind"),
+		Trigger:      job.GetString("trigger"),
+		Status:       job.GetString("status"),
+		Error:        job.GetString("error"),
+		SupersededBy: job.GetString("superseded_by"),
+		QueuedAt:     job.GetString("created"),
+		StartedAt:    job.GetString("started_at"),
+		FinishedAt:   job.GetString("finished_at"),
+	}
+	if view.Status == StatusQueued {
+		view.Position = h.service.Position(job)
+	}
+	if d, err := h.service.app.Dao().FindFirstRecordByData("deployments", "job", job.Id); err == nil {
+		view.DeploymentID = d.Id
+	}
+	return view
+}
+
+func (h *Handler) handleGetJob(c echo.Context) error {
+	job, err := h.service.Find(c.PathParam("jobId"))
+	if err != nil || !auth.CanAccessProject(h.service.app, c, job.GetString("project")) {
+		return apis.NewNotFoundError("Job not found", err)
+	}
+	return c.JSON(200, h.toView(job))
+}
+
+func (h *Handler) handleListJobs(c echo.Context) error {
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	jobs, err := h.service.List(c.PathParam("id"), limit)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to list jobs", err)
+	}
+
+	items := make([]jobView, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, h.toView(job))
+	}
+	return c.JSON(200, items)
+}
+
+// JobResponse is the body returned by endpoints that enqueue work
+func JobResponse(job *models.Record, message string) map[string]string {
+	return map[string]string{
+		"status":  job.GetString("status"),
+		"job_id":  job.Id,
+		"message": message,
+	}
+}
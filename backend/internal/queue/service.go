@@ -0,0 +1,319 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Job kinds
+const (
+	KindDeploy   = "deploy"   // Registry image deploy (orchestrator, Woodpecker callback)
+	KindRedeploy = "redeploy" // Build/recreate from project config (dashboard, token webhook)
+	KindRollback = "rollback" // Restore an earlier deployment
+	KindScale    = "scale"    // Change the replica count of the running release
+	KindTeardown = "teardown" // Remove the project's containers, route and record (previews)
+)
+
+// supersedeGroups lists, per kind, the queued kinds a new job replaces.
+// Any release (deploy/redeploy/rollback) replaces another, but a scale job must
+// never drop a queued release (and vice versa).
+var supersedeGroups = map[string][]string{
+	KindDeploy:   {KindDeploy, KindRedeploy, KindRollback},
+	KindRedeploy: {KindDeploy, KindRedeploy, KindRollback},
+	KindRollback: {KindDeploy, KindRedeploy, KindRollback},
+	KindScale:    {KindScale},
+	KindTeardown: {KindDeploy, KindRedeploy, KindRollback, KindScale, KindTeardown},
+}
+
+// Job status values
+const (
+	StatusQueued     = "queued"
+	StatusRunning    = "running"
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+	StatusSuperseded = "superseded" // Replaced by a newer job before it started
+)
+
+// Executor runs one job. The job record is fresh from the DB.
+type Executor func(ctx context.Context, job *models.Record) error
+
+// Service is a persistent deploy queue backed by the `deploy_jobs` collection.
+// Rules: at most one running job per project, at most `concurrency` running jobs overall
+// (not counting jobs that gave their slot back with ReleaseSlot),
+// and a newer job for a project supersedes its still-queued jobs of the same group (see supersedeGroups).
+type Service struct {
+	app         core.App
+	concurrency int
+	executors   map[string]Executor
+
+	mu       sync.Mutex
+	active   map[string]bool // projectID -> has running job
+	released map[string]bool // projectID -> running job gave its slot back (ReleaseSlot)
+	running  int
+	wake     chan struct{}
+}
+
+// NewService creates a new deploy queue. concurrency <= 0 means 1.
+func NewService(app core.App, concurrency int) *Service {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Service{
+		app:         app,
+		concurrency: concurrency,
+		executors:   make(map[string]Executor),
+		active:      make(map[string]bool),
+		released:    make(map[string]bool),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Register binds an executor to a job kind
+func (s *Service) Register(kind string, exec Executor) {
+	s.executors[kind] = exec
+}
+
+// Start recovers jobs interrupted by a restart and starts the dispatcher
+func (s *Service) Start() {
+	interrupted, err := s.app.Dao().FindRecordsByFilter(
+		"deploy_jobs", "status = {:status}", "created", 0, 0,
+		dbx.Params{"status": StatusRunning},
+	)
+	if err == nil {
+		for _, job := range interrupted {
+			log.Printf("♻️ Re-queueing job %s interrupted by restart", job.Id)
+			job.Set("status", StatusQueued)
+			job.Set("started_at", "")
+			s.save(job)
+		}
+	}
+
+	go s.loop()
+	s.notify()
+}
+
+// Enqueue adds a job for the project and supersedes its older queued jobs of the same group
+func (s *Service) Enqueue(projectID string, kind string, trigger string, params map[string]any) (*models.Record, error) {
+	if _, ok := s.executors[kind]; !ok {
+		return nil, fmt.Errorf("unknown job kind: %s", kind)
+	}
+
+	collection, err := s.app.Dao().FindCollectionByNameOrId("deploy_jobs")
+	if err != nil {
+		return nil, err
+	}
+
+	job := models.NewRecord(collection)
+	job.Set("project", projectID)
+	job.Set("kind", kind)
+	job.Set("trigger", trigger)
+	job.Set("params", params)
+	job.Set("status", StatusQueued)
+
+	// Lock so a dispatch can't start a job we are about to supersede
+	s.mu.Lock()
+	err = s.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		if err := txDao.SaveRecord(job); err != nil {
+			return err
+		}
+
+		pending, err := txDao.FindRecordsByFilter(
+			"deploy_jobs", "project = {:project} && status = {:status} && id != {:id}", "created", 0, 0,
+			dbx.Params{"project": projectID, "status": StatusQueued, "id": job.Id},
+		)
+		if err != nil {
+			return err
+		}
+		for _, old := range pending {
+			if !supersedes(kind, old.GetString("kind")) {
+				continue
+			}
+			log.Printf("⏭️ Job %s superseded by %s", old.Id, job.Id)
+			old.Set("status", StatusSuperseded)
+			old.Set("superseded_by", job.Id)
+			old.Set("finished_at", types.NowDateTime())
+			if err := txDao.SaveRecord(old); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📥 Queued %s job %s for project %s (trigger: %s)", kind, job.Id, projectID, trigger)
+	s.notify()
+	return job, nil
+}
+
+func supersedes(kind string, queued string) bool {
+	for _, k := range supersedeGroups[kind] {
+		if k == queued {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns a single job
+func (s *Service) Find(jobID string) (*models.Record, error) {
+	return s.app.Dao().FindRecordById("deploy_jobs", jobID)
+}
+
+// List returns the jobs of a project, newest first
+func (s *Service) List(projectID string, limit int) ([]*models.Record, error) {
+	return s.app.Dao().FindRecordsByFilter(
+		"deploy_jobs", "project = {:project}", "-created", limit, 0,
+		dbx.Params{"project": projectID},
+	)
+}
+
+// HasPending reports whether the project has a queued or running job
+func (s *Service) HasPending(projectID string) bool {
+	job, err := s.app.Dao().FindFirstRecordByFilter(
+		"deploy_jobs", "project = {:project} && (status = {:queued} || status = {:running})",
+		dbx.Params{"project": projectID, "queued": StatusQueued, "running": StatusRunning},
+	)
+	return err == nil && job != nil
+}
+
+// Position returns how many queued jobs are ahead of the given one (0 = next)
+func (s *Service) Position(job *models.Record) int {
+	ahead, err := s.app.Dao().FindRecordsByFilter(
+		"deploy_jobs", "status = {:status} && created < {:created}", "", 0, 0,
+		dbx.Params{"status": StatusQueued, "created": job.GetString("created")},
+	)
+	if err != nil {
+		return 0
+	}
+	return len(ahead)
+}
+
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) loop() {
+	// The ticker is a safety net for jobs inserted directly into the collection
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		}
+		s.dispatch()
+	}
+}
+
+func (s *Service) dispatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running >= s.concurrency {
+		return
+	}
+
+	queued, err := s.app.Dao().FindRecordsByFilter(
+		"deploy_jobs", "status = {:status}", "created", 100, 0,
+		dbx.Params{"status": StatusQueued},
+	)
+	if err != nil {
+		log.Printf("⚠️ Failed to read deploy queue: %v", err)
+		return
+	}
+
+	for _, job := range queued {
+		if s.running >= s.concurrency {
+			return
+		}
+		projectID := job.GetString("project")
+		if s.active[projectID] {
+			continue
+		}
+
+		job.Set("status", StatusRunning)
+		job.Set("started_at", types.NowDateTime())
+		if err := s.app.Dao().SaveRecord(job); err != nil {
+			log.Printf("⚠️ Failed to start job %s: %v", job.Id, err)
+			continue
+		}
+
+		s.active[projectID] = true
+		s.running++
+		go s.run(job)
+	}
+}
+
+func (s *Service) run(job *models.Record) {
+	projectID := job.GetString("project")
+	defer func() {
+		s.mu.Lock()
+		if !s.released[projectID] {
+			s.running--
+		}
+		delete(s.active, projectID)
+		delete(s.released, projectID)
+		s.mu.Unlock()
+		s.notify()
+	}()
+
+	log.Printf("⚙️ Running %s job %s for project %s", job.GetString("kind"), job.Id, projectID)
+
+	err := s.execute(job)
+
+	job.Set("finished_at", types.NowDateTime())
+	if err != nil {
+		log.Printf("❌ Job %s failed: %v", job.Id, err)
+		job.Set("status", StatusFailed)
+		job.Set("error", err.Error())
+	} else {
+		job.Set("status", StatusDone)
+	}
+	s.save(job)
+}
+
+// ReleaseSlot stops the running job of a project from counting toward the concurrency
+// limit, for jobs that mostly wait (a canary between its steps). The project stays
+// locked: its next job still waits for this one to finish.
+func (s *Service) ReleaseSlot(projectID string) {
+	s.mu.Lock()
+	if !s.active[projectID] || s.released[projectID] {
+		s.mu.Unlock()
+		return
+	}
+	s.released[projectID] = true
+	s.running--
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Service) execute(job *models.Record) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return s.executors[job.GetString("kind")](context.Background(), job)
+}
+
+func (s *Service) save(job *models.Record) {
+	if err := s.app.Dao().SaveRecord(job); err != nil {
+		log.Printf("⚠️ Failed to update job %s: %v", job.Id, err)
+	}
+}
@@ -0,0 +1,78 @@
+package quota
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Handler exposes quota usage
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new quota handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the quota endpoints
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/quota", h.handleGetQuota)
+}
+
+type quotaView struct {
+	User      string `json:"user"`
+	Limited   bool   `json:"limited"` // False when no quota applies
+	Quota     Quota  `json:"quota"`
+	Usage     Usage  `json:"usage"`
+	Remaining Quota  `json:"remaining"` // Zero for unlimited resources
+}
+
+// handleGetQuota returns the caller's quota and current usage. Admins pass ?user=.
+// URL: GET /api/senvanda/quota
+func (h *Handler) handleGetQuota(c echo.Context) error {
+	userID := ""
+	if admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin); admin != nil {
+		userID = c.QueryParam("user")
+	} else if user, _ := c.Get(apis.ContextAuthRecordKey).(*models.Record); user != nil {
+		userID = user.Id
+	}
+	if userID == "" {
+		return apis.NewUnauthorizedError("Sign in to see your quota", nil)
+	}
+
+	q := h.service.For(userID)
+	usage, err := h.service.Usage(userID, "")
+	if err != nil {
+		return apis.NewBadRequestError("Failed to compute usage", err)
+	}
+
+	return c.JSON(http.StatusOK, quotaView{
+		User:      userID,
+		Limited:   q != (Quota{}),
+		Quota:     q,
+		Usage:     usage,
+		Remaining: remaining(q, usage),
+	})
+}
+
+func remaining(q Quota, u Usage) Quota {
+	var r Quota
+	if q.MaxProjects > 0 {
+		r.MaxProjects = max(q.MaxProjects-u.Projects, 0)
+	}
+	if q.MaxCPU > 0 {
+		r.MaxCPU = max(q.MaxCPU-u.CPU, 0)
+	}
+	if q.MaxMemoryMB > 0 {
+		r.MaxMemoryMB = max(q.MaxMemoryMB-u.MemoryMB, 0)
+	}
+	if q.MaxVolumes > 0 {
+		r.MaxVolumes = max(q.MaxVolumes-u.Volumes, 0)
+	}
+	r.MaxReplicas = q.MaxReplicas // Per project, not consumed
+	return r
+}
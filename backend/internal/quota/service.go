@@ -0,0 +1,203 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// Resources named in quota errors
+const (
+	ResourceProjects = "projects"
+	ResourceCPU      = "cpu"
+	ResourceMemory   = "memory"
+	ResourceVolumes  = "volumes"
+	ResourceReplicas = "replicas"
+)
+
+// Quota holds the limits of a user. Zero means unlimited.
+type Quota struct {
+	MaxProjects int     `json:"max_projects"`
+	MaxCPU      float64 `json:"max_cpu"` // Cores, summed over all replicas of all projects
+	MaxMemoryMB int64   `json:"max_memory_mb"`
+	MaxVolumes  int     `json:"max_volumes"`
+	MaxReplicas int     `json:"max_replicas"` // Per project
+}
+
+// Usage is what a user's projects reserve through their limits. A project reserves
+// its CPU and memory once per replica; with autoscaling, for the maximum replicas.
+type Usage struct {
+	Projects int     `json:"projects"`
+	CPU      float64 `json:"cpu"`
+	MemoryMB int64   `json:"memory_mb"`
+	Volumes  int     `json:"volumes"`
+	Replicas int     `json:"replicas"` // Highest of any project
+}
+
+// demand is what one project reserves
+type demand struct {
+	cpu      float64
+	memoryMB int64
+	volumes  int
+	replicas int
+}
+
+func demandOf(spec orchestrator.Spec) demand {
+	replicas := spec.Replicas
+	if spec.Autoscale != nil && spec.Autoscale.MaxReplicas > replicas {
+		replicas = spec.Autoscale.MaxReplicas
+	}
+	return demand{
+		cpu:      spec.CPU * float64(replicas),
+		memoryMB: spec.MemoryMB * int64(replicas),
+		volumes:  len(spec.Binds),
+		replicas: replicas,
+	}
+}
+
+// ExceededError is returned when a change would take a user over a quota
+type ExceededError struct {
+	Resource  string  `json:"resource"`
+	Limit     float64 `json:"limit"`
+	Requested float64 `json:"requested"` // Total after the change
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Resource {
+	case ResourceCPU:
+		return fmt.Sprintf("quota exceeded: %.2f CPU cores requested, plan allows %.2f", e.Requested, e.Limit)
+	case ResourceMemory:
+		return fmt.Sprintf("quota exceeded: %.0fMB memory requested, plan allows %.0fMB", e.Requested, e.Limit)
+	case ResourceReplicas:
+		return fmt.Sprintf("quota exceeded: %.0f replicas requested, plan allows %.0f per project", e.Requested, e.Limit)
+	default:
+		return fmt.Sprintf("quota exceeded: %.0f %s requested, plan allows %.0f", e.Requested, e.Resource, e.Limit)
+	}
+}
+
+// IsExceeded reports whether err is a quota refusal
+func IsExceeded(err error) bool {
+	var exceeded *ExceededError
+	return errors.As(err, &exceeded)
+}
+
+// Service resolves quotas from the `quotas` collection and checks changes against them.
+// A quota record without a user is the default for every user; without any record
+// nothing is limited.
+type Service struct {
+	app core.App
+}
+
+// NewService creates a new quota service
+func NewService(app core.App) *Service {
+	return &Service{app: app}
+}
+
+// For returns the quota that applies to a user
+func (s *Service) For(userID string) Quota {
+	records, err := s.app.Dao().FindRecordsByFilter(
+		"quotas", "user = {:user} || user = ''", "-user", 1, 0, dbx.Params{"user": userID},
+	)
+	if err != nil || len(records) == 0 {
+		return Quota{}
+	}
+	r := records[0]
+	return Quota{
+		MaxProjects: r.GetInt("max_projects"),
+		MaxCPU:      r.GetFloat("max_cpu"),
+		MaxMemoryMB: int64(r.GetInt("max_memory_mb")),
+		MaxVolumes:  r.GetInt("max_volumes"),
+		MaxReplicas: r.GetInt("max_replicas"),
+	}
+}
+
+// Usage sums what the user's projects reserve. Drafts count as projects but reserve
+// nothing until deployed. The project `except` is left out.
+func (s *Service) Usage(userID string, except string) (Usage, error) {
+	var usage Usage
+	projects, err := s.app.Dao().FindRecordsByFilter(
+		"projects", "user = {:user} && id != {:except}", "", 0, 0, dbx.Params{"user": userID, "except": except},
+	)
+	if err != nil {
+		return usage, err
+	}
+
+	for _, p := range projects {
+		usage.Projects++
+		if p.GetString("status") == "draft" {
+			continue
+		}
+		spec, err := orchestrator.SpecFromProject(p)
+		if err != nil {
+			continue
+		}
+		usage.add(demandOf(spec))
+	}
+	return usage, nil
+}
+
+func (u *Usage) add(d demand) {
+	u.CPU += d.cpu
+	u.MemoryMB += d.memoryMB
+	u.Volumes += d.volumes
+	if d.replicas > u.Replicas {
+		u.Replicas = d.replicas
+	}
+}
+
+// CheckNewProject refuses a project the owner has no room for
+func (s *Service) CheckNewProject(userID string) error {
+	q := s.For(userID)
+	if q.MaxProjects == 0 {
+		return nil
+	}
+	usage, err := s.Usage(userID, "")
+	if err != nil {
+		return err
+	}
+	if usage.Projects+1 > q.MaxProjects {
+		return &ExceededError{Resource: ResourceProjects, Limit: float64(q.MaxProjects), Requested: float64(usage.Projects + 1)}
+	}
+	return nil
+}
+
+// Admit checks the project running with spec against its owner's quota. The
+// project's stored settings are replaced by spec, so a redeploy is not counted twice.
+// Projects without an owner (seeded, adopted by an admin) are not limited.
+// Matches orchestrator.Admission.
+func (s *Service) Admit(project *models.Record, spec orchestrator.Spec) error {
+	userID := project.GetString("user")
+	if userID == "" {
+		return nil
+	}
+	q := s.For(userID)
+	if q == (Quota{}) {
+		return nil
+	}
+
+	usage, err := s.Usage(userID, project.Id)
+	if err != nil {
+		return err
+	}
+	next := demandOf(spec)
+	usage.add(next)
+
+	if q.MaxReplicas > 0 && next.replicas > q.MaxReplicas {
+		return &ExceededError{Resource: ResourceReplicas, Limit: float64(q.MaxReplicas), Requested: float64(next.replicas)}
+	}
+	if q.MaxCPU > 0 && usage.CPU > q.MaxCPU {
+		return &ExceededError{Resource: ResourceCPU, Limit: q.MaxCPU, Requested: usage.CPU}
+	}
+	if q.MaxMemoryMB > 0 && usage.MemoryMB > q.MaxMemoryMB {
+		return &ExceededError{Resource: ResourceMemory, Limit: float64(q.MaxMemoryMB), Requested: float64(usage.MemoryMB)}
+	}
+	if q.MaxVolumes > 0 && usage.Volumes > q.MaxVolumes {
+		return &ExceededError{Resource: ResourceVolumes, Limit: float64(q.MaxVolumes), Requested: float64(usage.Volumes)}
+	}
+	return nil
+}
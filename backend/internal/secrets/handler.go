@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// Handler exposes master key rotation
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new secrets handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the secrets endpoints (admins only)
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.POST("/secrets/rotate", h.handleRotate, auth.RequireAdmin())
+}
+
+// handleRotate re-encrypts every secret with the current master key. Restart with the
+// new key in SENVANDA_SECRET_KEY and the old one in SENVANDA_SECRET_KEY_PREVIOUS first;
+// once this succeeds the old key can be dropped.
+// URL: POST /api/senvanda/secrets/rotate
+func (h *Handler) handleRotate(c echo.Context) error {
+	audit.SetAction(c, "secrets.rotate")
+	result, err := h.service.Rotate()
+	audit.Param(c, "key_id", result.KeyID)
+	audit.Param(c, "rotated", result.Secrets)
+	if errors.Is(err, ErrNoKey) {
+		return apis.NewBadRequestError(err.Error(), err)
+	}
+	if err != nil {
+		return apis.NewBadRequestError("Failed to rotate secrets: "+err.Error(), err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
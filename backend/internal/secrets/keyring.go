@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix marks an encrypted value: enc:v1:<key id>:<base64 nonce+ciphertext>
+const Prefix = "enc:v1:"
+
+// minKeyLength keeps master keys out of password territory
+const minKeyLength = 32
+
+var (
+	// ErrNoKey is returned when a secret has to be encrypted or decrypted without a master key
+	ErrNoKey = errors.New("secret env vars need a master key: set SENVANDA_SECRET_KEY (32+ characters)")
+
+	// ErrUnknownKey is returned for values encrypted with a key that is no longer configured
+	ErrUnknownKey = errors.New("secret was encrypted with a key that is not configured (add it to SENVANDA_SECRET_KEY_PREVIOUS)")
+)
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+func newKey(master string) (key, error) {
+	if len(master) < minKeyLength {
+		return key{}, fmt.Errorf("secret key must be at least %d characters", minKeyLength)
+	}
+	sum := sha256.Sum256([]byte(master))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return key{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return key{}, err
+	}
+	// The id only tells keys apart; it is derived so it reveals nothing about the key
+	id := sha256.Sum256(append([]byte("senvanda-secret-key-id:"), sum[:]...))
+	return key{id: hex.EncodeToString(id[:4]), aead: aead}, nil
+}
+
+// Keyring encrypts with the current master key and decrypts with the current or a
+// previous one, so keys can be rotated without losing secrets. A nil Keyring has no
+// key: it refuses to encrypt and can only pass plaintext through.
+type Keyring struct {
+	current  key
+	previous []key
+}
+
+// NewKeyring builds a keyring from the current master key and retired ones
+func NewKeyring(current string, previous ...string) (*Keyring, error) {
+	k := &Keyring{}
+	var err error
+	if k.current, err = newKey(current); err != nil {
+		return nil, err
+	}
+	for _, master := range previous {
+		old, err := newKey(master)
+		if err != nil {
+			return nil, fmt.Errorf("previous %w", err)
+		}
+		k.previous = append(k.previous, old)
+	}
+	return k, nil
+}
+
+// FromEnv reads SENVANDA_SECRET_KEY and the comma-separated SENVANDA_SECRET_KEY_PREVIOUS.
+// Without a current key it returns nil: secrets cannot be stored until one is set.
+func FromEnv() (*Keyring, error) {
+	current := os.Getenv("SENVANDA_SECRET_KEY")
+	if current == "" {
+		return nil, nil
+	}
+	var previous []string
+	for _, master := range strings.Split(os.Getenv("SENVANDA_SECRET_KEY_PREVIOUS"), ",") {
+		if master = strings.TrimSpace(master); master != "" {
+			previous = append(previous, master)
+		}
+	}
+	return NewKeyring(current, previous...)
+}
+
+// KeyID identifies the current key in encrypted values
+func (k *Keyring) KeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.current.id
+}
+
+// IsEncrypted reports whether value is a ciphertext produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// keyIDOf returns the key id of an encrypted value
+func keyIDOf(value string) string {
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	return id
+}
+
+// Encrypt seals plain with the current key
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, k.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.current.aead.Seal(nonce, nonce, []byte(plain), []byte(k.current.id))
+	return Prefix + k.current.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value from Encrypt. Values that are not encrypted are returned as-is.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	id, payload, ok := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	for _, candidate := range append([]key{k.current}, k.previous...) {
+		if candidate.id != id {
+			continue
+		}
+		sealed, err := base64.RawStdEncoding.DecodeString(payload)
+		if err != nil || len(sealed) < candidate.aead.NonceSize() {
+			return "", errors.New("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:candidate.aead.NonceSize()], sealed[candidate.aead.NonceSize():]
+		plain, err := candidate.aead.Open(nil, nonce, ciphertext, []byte(id))
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+		return string(plain), nil
+	}
+	return "", ErrUnknownKey
+}
+
+// NeedsRotation reports whether value is encrypted with a key other than the current one
+func (k *Keyring) NeedsRotation(value string) bool {
+	return k != nil && IsEncrypted(value) && keyIDOf(value) != k.current.id
+}
+
+// DecryptEnv decrypts the values of KEY=value entries right before they are handed
+// to a container. It is the only place secrets leave their encrypted form.
+func (k *Keyring) DecryptEnv(env []string) ([]string, error) {
+	out := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		plain, err := k.Decrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		out = append(out, name+"="+plain)
+	}
+	return out, nil
+}
@@ -0,0 +1,353 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// Mask replaces secret values in every API response. Sending it back unchanged
+// (e.g. a dashboard saving the whole settings object) keeps the stored secret.
+const Mask = "********"
+
+// envVar is an entry of the `envVars` list in project settings
+type envVar struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// UnmarshalJSON accepts numbers and booleans as values, so such settings still get sealed
+func (e *envVar) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key    string      `json:"key"`
+		Value  interface{} `json:"value"`
+		Secret bool        `json:"secret"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	value, err := orchestrator.EnvValue(raw.Value)
+	if err != nil {
+		return fmt.Errorf("env var %s: %w", raw.Key, err)
+	}
+	*e = envVar{Key: raw.Key, Value: value, Secret: raw.Secret}
+	return nil
+}
+
+// Service keeps env vars marked `secret` encrypted in project settings and deployment
+// snapshots, and masked in what PocketBase sends out
+type Service struct {
+	app  core.App
+	keys *Keyring
+}
+
+// NewService creates a new secrets service. keys may be nil (no master key configured).
+func NewService(app core.App, keys *Keyring) *Service {
+	return &Service{app: app, keys: keys}
+}
+
+// Keys returns the keyring, for the code that builds container env
+func (s *Service) Keys() *Keyring {
+	return s.keys
+}
+
+// BindHooks encrypts secrets on every project save (records API and Dao alike) and
+// masks them in record API responses and realtime messages
+func (s *Service) BindHooks() {
+	s.app.OnModelBeforeCreate("projects").Add(func(e *core.ModelEvent) error {
+		return s.seal(e.Model.(*models.Record))
+	})
+	s.app.OnModelBeforeUpdate("projects").Add(func(e *core.ModelEvent) error {
+		return s.seal(e.Model.(*models.Record))
+	})
+
+	s.app.OnRecordsListRequest().Add(func(e *core.RecordsListEvent) error {
+		for _, r := range e.Records {
+			MaskRecord(r)
+		}
+		return nil
+	})
+	s.app.OnRecordViewRequest().Add(func(e *core.RecordViewEvent) error {
+		MaskRecord(e.Record)
+		return nil
+	})
+	s.app.OnRecordAfterCreateRequest().Add(func(e *core.RecordCreateEvent) error {
+		MaskRecord(e.Record)
+		return nil
+	})
+	s.app.OnRecordAfterUpdateRequest().Add(func(e *core.RecordUpdateEvent) error {
+		MaskRecord(e.Record)
+		return nil
+	})
+	s.app.OnRealtimeBeforeMessageSend().Add(func(e *core.RealtimeMessageEvent) error {
+		e.Message.Data = maskMessage(e.Message.Data)
+		return nil
+	})
+}
+
+// seal encrypts new secret values of a project and puts back the stored ciphertext
+// for secrets sent as Mask
+func (s *Service) seal(record *models.Record) error {
+	settings, envs, err := decodeSettings(record.GetString("settings"))
+	if err != nil || envs == nil {
+		return nil // Unparseable settings are reported by the deploy, not here
+	}
+
+	stored := map[string]envVar{}
+	if !record.IsNew() {
+		if _, old, err := decodeSettings(record.OriginalCopy().GetString("settings")); err == nil {
+			for _, e := range old {
+				if e.Secret {
+					stored[e.Key] = e
+				}
+			}
+		}
+	}
+
+	changed := false
+	for i, e := range envs {
+		switch {
+		case e.Value == Mask:
+			old, ok := stored[e.Key]
+			if !ok {
+				return fmt.Errorf("secret env var %s has no stored value to keep", e.Key)
+			}
+			envs[i] = old
+			changed = true
+		case e.Secret && !IsEncrypted(e.Value):
+			sealed, err := s.keys.Encrypt(e.Value)
+			if err != nil {
+				return err
+			}
+			envs[i].Value = sealed
+			changed = true
+		}
+	}
+	if changed {
+		settings["envVars"] = envs
+		record.Set("settings", settings)
+	}
+	return nil
+}
+
+// decodeSettings parses project settings and their env vars (nil when there are none)
+func decodeSettings(raw string) (map[string]any, []envVar, error) {
+	settings := map[string]any{}
+	if raw == "" || raw == "null" {
+		return settings, nil, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return nil, nil, err
+	}
+	if settings["envVars"] == nil {
+		return settings, nil, nil
+	}
+	var envs []envVar
+	encoded, _ := json.Marshal(settings["envVars"])
+	if err := json.Unmarshal(encoded, &envs); err != nil {
+		return nil, nil, err
+	}
+	return settings, envs, nil
+}
+
+// maskSettings masks the secret env vars of a decoded settings object in place
+func maskSettings(settings map[string]any) bool {
+	list, _ := settings["envVars"].([]any)
+	masked := false
+	for _, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		value, _ := entry["value"].(string)
+		if secret, _ := entry["secret"].(bool); secret || IsEncrypted(value) {
+			entry["value"] = Mask
+			entry["secret"] = true
+			masked = true
+		}
+	}
+	return masked
+}
+
+// MaskRecord masks secrets in a project (settings) or deployment (snapshot) record
+// and in the records it expands. Only for records about to be sent, never saved.
+func MaskRecord(record *models.Record) {
+	if record == nil {
+		return
+	}
+	switch record.Collection().Name {
+	case "projects":
+		settings := map[string]any{}
+		if json.Unmarshal([]byte(record.GetString("settings")), &settings) == nil && maskSettings(settings) {
+			record.Set("settings", settings)
+		}
+	case "deployments":
+		snapshot := map[string]any{}
+		if json.Unmarshal([]byte(record.GetString("snapshot")), &snapshot) == nil {
+			if settings, ok := snapshot["settings"].(map[string]any); ok && maskSettings(settings) {
+				record.Set("snapshot", snapshot)
+			}
+		}
+	}
+	for _, expanded := range record.Expand() {
+		switch v := expanded.(type) {
+		case *models.Record:
+			MaskRecord(v)
+		case []*models.Record:
+			for _, r := range v {
+				MaskRecord(r)
+			}
+		}
+	}
+}
+
+// maskMessage masks the record of a realtime message ({"action": ..., "record": {...}})
+func maskMessage(data []byte) []byte {
+	if !strings.Contains(string(data), "envVars") {
+		return data
+	}
+	var message map[string]any
+	if err := json.Unmarshal(data, &message); err != nil {
+		return data
+	}
+	record, ok := message["record"].(map[string]any)
+	if !ok || !maskJSON(record) {
+		return data
+	}
+	masked, err := json.Marshal(message)
+	if err != nil {
+		return data
+	}
+	return masked
+}
+
+// maskJSON masks an exported record and its expanded records
+func maskJSON(record map[string]any) bool {
+	masked := false
+	if settings, ok := record["settings"].(map[string]any); ok && maskSettings(settings) {
+		masked = true
+	}
+	if snapshot, ok := record["snapshot"].(map[string]any); ok {
+		if settings, ok := snapshot["settings"].(map[string]any); ok && maskSettings(settings) {
+			masked = true
+		}
+	}
+	expand, _ := record["expand"].(map[string]any)
+	for _, value := range expand {
+		switch v := value.(type) {
+		case map[string]any:
+			masked = maskJSON(v) || masked
+		case []any:
+			for _, item := range v {
+				if r, ok := item.(map[string]any); ok {
+					masked = maskJSON(r) || masked
+				}
+			}
+		}
+	}
+	return masked
+}
+
+// RotateResult counts what Rotate re-encrypted
+type RotateResult struct {
+	KeyID       string `json:"key_id"`
+	Projects    int    `json:"projects"`
+	Deployments int    `json:"deployments"`
+	Secrets     int    `json:"secrets"`
+}
+
+// Rotate re-encrypts every secret not sealed with the current key: project settings
+// and deployment snapshots (so rollbacks keep working once the old key is removed).
+// Run it after moving the old key to SENVANDA_SECRET_KEY_PREVIOUS.
+func (s *Service) Rotate() (RotateResult, error) {
+	result := RotateResult{KeyID: s.keys.KeyID()}
+	if s.keys == nil {
+		return result, ErrNoKey
+	}
+
+	projects, err := s.app.Dao().FindRecordsByFilter("projects", "settings ~ {:marker}", "", 0, 0, dbx.Params{"marker": Prefix})
+	if err != nil {
+		return result, err
+	}
+	for _, project := range projects {
+		settings, envs, err := decodeSettings(project.GetString("settings"))
+		if err != nil {
+			continue
+		}
+		n, err := s.rotateEnv(envs)
+		if err != nil {
+			return result, fmt.Errorf("project %s: %w", project.GetString("name"), err)
+		}
+		if n == 0 {
+			continue
+		}
+		settings["envVars"] = envs
+		project.Set("settings", settings)
+		if err := s.app.Dao().SaveRecord(project); err != nil {
+			return result, err
+		}
+		result.Projects++
+		result.Secrets += n
+	}
+
+	deployments, err := s.app.Dao().FindRecordsByFilter("deployments", "snapshot ~ {:marker}", "", 0, 0, dbx.Params{"marker": Prefix})
+	if err != nil {
+		return result, err
+	}
+	for _, deployment := range deployments {
+		snapshot := map[string]any{}
+		if err := json.Unmarshal([]byte(deployment.GetString("snapshot")), &snapshot); err != nil {
+			continue
+		}
+		raw, _ := json.Marshal(snapshot["settings"])
+		settings, envs, err := decodeSettings(string(raw))
+		if err != nil {
+			continue
+		}
+		n, err := s.rotateEnv(envs)
+		if err != nil {
+			return result, fmt.Errorf("deployment %s: %w", deployment.Id, err)
+		}
+		if n == 0 {
+			continue
+		}
+		settings["envVars"] = envs
+		snapshot["settings"] = settings
+		deployment.Set("snapshot", snapshot)
+		if err := s.app.Dao().SaveRecord(deployment); err != nil {
+			return result, err
+		}
+		result.Deployments++
+		result.Secrets += n
+	}
+
+	log.Printf("🔐 Rotated %d secret(s) to key %s (%d projects, %d deployments)", result.Secrets, result.KeyID, result.Projects, result.Deployments)
+	return result, nil
+}
+
+// rotateEnv re-encrypts in place the values sealed with an old key
+func (s *Service) rotateEnv(envs []envVar) (int, error) {
+	n := 0
+	for i, e := range envs {
+		if !s.keys.NeedsRotation(e.Value) {
+			continue
+		}
+		plain, err := s.keys.Decrypt(e.Value)
+		if err != nil {
+			return n, fmt.Errorf("env %s: %w", e.Key, err)
+		}
+		if envs[i].Value, err = s.keys.Encrypt(plain); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,390 @@
+package team
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// Handler exposes teams, memberships and invitations
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new team handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the team endpoints. The group must require auth.
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/teams", h.handleListTeams)
+	g.POST("/teams", h.handleCreateTeam)
+	g.GET("/teams/:teamId", h.handleGetTeam)
+	g.DELETE("/teams/:teamId", h.handleDeleteTeam)
+	g.PATCH("/teams/:teamId/members/:userId", h.handleSetRole)
+	g.DELETE("/teams/:teamId/members/:userId", h.handleRemoveMember)
+	g.GET("/teams/:teamId/invitations", h.handleListInvitations)
+	g.POST("/teams/:teamId/invitations", h.handleInvite)
+	g.DELETE("/teams/:teamId/invitations/:invitationId", h.handleRevoke)
+	g.GET("/invitations/:token", h.handleGetInvitation)
+	g.POST("/invitations/:token/accept", h.handleAccept)
+	g.POST("/deploy/:id/team", h.handleMoveProject)
+}
+
+type teamView struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Role    string       `json:"role"` // Caller's role (owner for admins)
+	Created string       `json:"created"`
+	Members []memberView `json:"members,omitempty"`
+}
+
+type memberView struct {
+	User   string `json:"user"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role"`
+	Joined string `json:"joined"`
+}
+
+type invitationView struct {
+	ID        string `json:"id"`
+	Team      string `json:"team"`
+	TeamName  string `json:"team_name,omitempty"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at"`
+	URL       string `json:"url,omitempty"` // Only shown to team owners
+	EmailSent *bool  `json:"email_sent,omitempty"`
+	MailError string `json:"mail_error,omitempty"`
+}
+
+func toInvitationView(r *models.Record) invitationView {
+	return invitationView{
+		ID:        r.Id,
+		Team:      r.GetString("team"),
+		Email:     r.GetString("email"),
+		Role:      r.GetString("role"),
+		Status:    r.GetString("status"),
+		ExpiresAt: r.GetString("expires_at"),
+	}
+}
+
+// callerRole is the caller's role in the team; admins act as owners
+func (h *Handler) callerRole(c echo.Context, teamID string) string {
+	if auth.IsAdmin(c) {
+		return auth.RoleOwner
+	}
+	return auth.TeamRole(h.service.app, teamID, auth.UserID(c))
+}
+
+// loadTeam returns the `:teamId` team when the caller holds at least min in it.
+// Non-members get a 404 so team ids cannot be probed.
+func (h *Handler) loadTeam(c echo.Context, min string) (*models.Record, string, error) {
+	team, err := h.service.app.Dao().FindRecordById("teams", c.PathParam("teamId"))
+	if err != nil {
+		return nil, "", apis.NewNotFoundError("Team not found", err)
+	}
+	role := h.callerRole(c, team.Id)
+	if role == "" {
+		return nil, "", apis.NewNotFoundError("Team not found", nil)
+	}
+	if !auth.HasRole(role, min) {
+		return nil, "", apis.NewForbiddenError("Only team owners can perform this action.", nil)
+	}
+	return team, role, nil
+}
+
+// handleListTeams returns the caller's teams (every team for admins)
+// URL: GET /api/senvanda/teams
+func (h *Handler) handleListTeams(c echo.Context) error {
+	teams, err := h.service.ForUser(auth.UserID(c))
+	if err != nil {
+		return apis.NewBadRequestError("Failed to list teams", err)
+	}
+	items := make([]teamView, 0, len(teams))
+	for _, t := range teams {
+		items = append(items, teamView{
+			ID:      t.Id,
+			Name:    t.GetString("name"),
+			Role:    h.callerRole(c, t.Id),
+			Created: t.Created.String(),
+		})
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleCreateTeam creates a team owned by the caller
+// URL: POST /api/senvanda/teams {"name": "Backend"}
+func (h *Handler) handleCreateTeam(c echo.Context) error {
+	user := auth.User(c)
+	if user == nil {
+		return apis.NewForbiddenError("Teams are created by users, not admins", nil)
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&payload); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+
+	team, err := h.service.Create(user, payload.Name)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to create team: "+err.Error(), err)
+	}
+	return c.JSON(http.StatusCreated, teamView{
+		ID:      team.Id,
+		Name:    team.GetString("name"),
+		Role:    auth.RoleOwner,
+		Created: team.Created.String(),
+	})
+}
+
+// handleGetTeam returns a team with its members
+// URL: GET /api/senvanda/teams/:teamId
+func (h *Handler) handleGetTeam(c echo.Context) error {
+	team, role, err := h.loadTeam(c, auth.RoleViewer)
+	if err != nil {
+		return err
+	}
+	members, err := h.service.Members(team.Id)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to list members", err)
+	}
+
+	view := teamView{
+		ID:      team.Id,
+		Name:    team.GetString("name"),
+		Role:    role,
+		Created: team.Created.String(),
+		Members: make([]memberView, 0, len(members)),
+	}
+	for _, m := range members {
+		member := memberView{
+			User:   m.GetString("user"),
+			Role:   m.GetString("role"),
+			Joined: m.Created.String(),
+		}
+		if user, err := h.service.app.Dao().FindRecordById("users", member.User); err == nil {
+			member.Email = user.Email()
+			member.Name = user.GetString("name")
+		}
+		view.Members = append(view.Members, member)
+	}
+	return c.JSON(http.StatusOK, view)
+}
+
+// handleDeleteTeam deletes a team; its projects go back to their personal owners
+// URL: DELETE /api/senvanda/teams/:teamId
+func (h *Handler) handleDeleteTeam(c echo.Context) error {
+	team, _, err := h.loadTeam(c, auth.RoleOwner)
+	if err != nil {
+		return err
+	}
+	if err := h.service.Delete(team); err != nil {
+		return apis.NewBadRequestError("Failed to delete team", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handleSetRole changes the role of a member
+// URL: PATCH /api/senvanda/teams/:teamId/members/:userId {"role": "developer"}
+func (h *Handler) handleSetRole(c echo.Context) error {
+	team, _, err := h.loadTeam(c, auth.RoleOwner)
+	if err != nil {
+		return err
+	}
+	var payload struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&payload); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+
+	audit.SetAction(c, "team.set_role")
+	audit.Param(c, "role", payload.Role)
+	member, err := h.service.SetRole(team.Id, c.PathParam("userId"), payload.Role)
+	if errors.Is(err, ErrNotMember) {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), err)
+	}
+	return c.JSON(http.StatusOK, memberView{
+		User:   member.GetString("user"),
+		Role:   member.GetString("role"),
+		Joined: member.Created.String(),
+	})
+}
+
+// handleRemoveMember removes a member. Owners remove anyone, members can leave.
+// URL: DELETE /api/senvanda/teams/:teamId/members/:userId
+func (h *Handler) handleRemoveMember(c echo.Context) error {
+	min := auth.RoleOwner
+	if c.PathParam("userId") == auth.UserID(c) {
+		min = auth.RoleViewer
+	}
+	team, _, err := h.loadTeam(c, min)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.RemoveMember(team.Id, c.PathParam("userId"))
+	if errors.Is(err, ErrNotMember) {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handleListInvitations returns the pending invitations of a team
+// URL: GET /api/senvanda/teams/:teamId/invitations
+func (h *Handler) handleListInvitations(c echo.Context) error {
+	team, _, err := h.loadTeam(c, auth.RoleOwner)
+	if err != nil {
+		return err
+	}
+	invitations, err := h.service.Invitations(team.Id)
+	if err != nil {
+		return apis.NewBadRequestError("Failed to list invitations", err)
+	}
+	items := make([]invitationView, 0, len(invitations))
+	for _, inv := range invitations {
+		view := toInvitationView(inv)
+		view.URL = h.service.InvitationURL(inv)
+		items = append(items, view)
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleInvite invites an email address to the team and emails the link
+// URL: POST /api/senvanda/teams/:teamId/invitations {"email": "dev@example.com", "role": "viewer"}
+func (h *Handler) handleInvite(c echo.Context) error {
+	team, _, err := h.loadTeam(c, auth.RoleOwner)
+	if err != nil {
+		return err
+	}
+	var payload struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.Bind(&payload); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+	if payload.Role == "" {
+		payload.Role = auth.RoleDeveloper
+	}
+
+	audit.SetAction(c, "team.invite")
+	audit.Param(c, "email", payload.Email)
+	audit.Param(c, "role", payload.Role)
+
+	inviter := auth.User(c)
+	invitation, err := h.service.Invite(team, inviter, payload.Email, payload.Role)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), err)
+	}
+
+	view := toInvitationView(invitation)
+	view.TeamName = team.GetString("name")
+	view.URL = h.service.InvitationURL(invitation)
+	sent := true
+	if err := h.service.SendInvitation(team, inviter, invitation); err != nil {
+		sent = false
+		view.MailError = err.Error()
+	}
+	view.EmailSent = &sent
+	return c.JSON(http.StatusCreated, view)
+}
+
+// handleRevoke cancels a pending invitation
+// URL: DELETE /api/senvanda/teams/:teamId/invitations/:invitationId
+func (h *Handler) handleRevoke(c echo.Context) error {
+	team, _, err := h.loadTeam(c, auth.RoleOwner)
+	if err != nil {
+		return err
+	}
+	if err := h.service.Revoke(team.Id, c.PathParam("invitationId")); err != nil {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handleGetInvitation shows an invitation before it is accepted
+// URL: GET /api/senvanda/invitations/:token
+func (h *Handler) handleGetInvitation(c echo.Context) error {
+	invitation, err := h.service.FindInvitation(c.PathParam("token"))
+	if err != nil {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	view := toInvitationView(invitation)
+	if team, err := h.service.app.Dao().FindRecordById("teams", view.Team); err == nil {
+		view.TeamName = team.GetString("name")
+	}
+	return c.JSON(http.StatusOK, view)
+}
+
+// handleAccept joins the caller to the invitation's team
+// URL: POST /api/senvanda/invitations/:token/accept
+func (h *Handler) handleAccept(c echo.Context) error {
+	user := auth.User(c)
+	if user == nil {
+		return apis.NewForbiddenError("Sign in as the invited user to accept", nil)
+	}
+
+	invitation, err := h.service.Accept(c.PathParam("token"), user)
+	if errors.Is(err, ErrEmailMismatch) {
+		return apis.NewForbiddenError(err.Error(), err)
+	}
+	if errors.Is(err, ErrInvitationInvalid) {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	if err != nil {
+		return apis.NewBadRequestError("Failed to accept invitation", err)
+	}
+	return c.JSON(http.StatusOK, toInvitationView(invitation))
+}
+
+// handleMoveProject shares a project with a team, or makes it personal again with
+// an empty team. Needs the owner role on the project and on the target team.
+// URL: POST /api/senvanda/deploy/:id/team {"team": "<team id>"}
+func (h *Handler) handleMoveProject(c echo.Context) error {
+	if auth.RoleFromContext(c) != auth.RoleOwner {
+		return apis.NewForbiddenError("Only project owners can change its team", nil)
+	}
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+	var payload struct {
+		Team string `json:"team"`
+	}
+	if err := c.Bind(&payload); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+	if payload.Team != "" {
+		if _, err := h.service.app.Dao().FindRecordById("teams", payload.Team); err != nil {
+			return apis.NewNotFoundError("Team not found", err)
+		}
+		if h.callerRole(c, payload.Team) != auth.RoleOwner {
+			return apis.NewForbiddenError("Only team owners can add projects to the team", nil)
+		}
+	}
+
+	audit.SetAction(c, "project.move_team")
+	audit.Param(c, "team", payload.Team)
+	if err := h.service.MoveProject(project, payload.Team); err != nil {
+		return apis.NewBadRequestError("Failed to change the project's team", err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"id": project.Id, "team": payload.Team})
+}
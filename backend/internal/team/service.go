@@ -0,0 +1,336 @@
+package team
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// InvitationTTL is how long an invitation link stays valid
+const InvitationTTL = 7 * 24 * time.Hour
+
+// Invitation states, stored in `team_invitations`
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+var (
+	// ErrLastOwner is returned when a change would leave a team without an owner
+	ErrLastOwner = errors.New("a team needs at least one owner")
+
+	// ErrNotMember is returned for users outside the team
+	ErrNotMember = errors.New("user is not a member of this team")
+
+	// ErrInvalidRole is returned for roles other than owner, developer and viewer
+	ErrInvalidRole = errors.New("role must be owner, developer or viewer")
+
+	// ErrInvitationInvalid is returned for unknown, used, revoked or expired invitations
+	ErrInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+	// ErrEmailMismatch is returned when an invitation is accepted by another account
+	ErrEmailMismatch = errors.New("this invitation was sent to a different email address")
+)
+
+// Service manages teams, their members and invitations. Access checks on projects
+// live in the auth package; this service only changes membership.
+type Service struct {
+	app core.App
+}
+
+// NewService creates a new team service
+func NewService(app core.App) *Service {
+	return &Service{app: app}
+}
+
+// Create creates a team with the user as its first owner
+func (s *Service) Create(user *models.Record, name string) (*models.Record, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("team name is required")
+	}
+
+	var team *models.Record
+	err := s.app.Dao().RunInTransaction(func(tx *daos.Dao) error {
+		teams, err := tx.FindCollectionByNameOrId("teams")
+		if err != nil {
+			return err
+		}
+		team = models.NewRecord(teams)
+		team.Set("name", name)
+		team.Set("created_by", user.Id)
+		if err := tx.SaveRecord(team); err != nil {
+			return err
+		}
+		return s.addMember(tx, team.Id, user.Id, auth.RoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("👥 Team '%s' created by %s", name, user.Email())
+	return team, nil
+}
+
+// ForUser returns the teams the user is a member of. An empty userID (admins) returns every team.
+func (s *Service) ForUser(userID string) ([]*models.Record, error) {
+	if userID == "" {
+		return s.app.Dao().FindRecordsByFilter("teams", "id != ''", "name", 0, 0)
+	}
+	ids := auth.TeamIDs(s.app, userID)
+	if len(ids) == 0 {
+		return []*models.Record{}, nil
+	}
+	return s.app.Dao().FindRecordsByIds("teams", ids)
+}
+
+// Members returns the memberships of a team, oldest first
+func (s *Service) Members(teamID string) ([]*models.Record, error) {
+	return s.app.Dao().FindRecordsByFilter("team_members", "team = {:team}", "created", 0, 0, dbx.Params{"team": teamID})
+}
+
+// Delete removes a team. Memberships and invitations go with it; its projects fall
+// back to their personal owner.
+func (s *Service) Delete(team *models.Record) error {
+	return s.app.Dao().DeleteRecord(team)
+}
+
+// SetRole changes the role of a member
+func (s *Service) SetRole(teamID string, userID string, role string) (*models.Record, error) {
+	if !auth.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	member, err := s.member(teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.GetString("role") == auth.RoleOwner && role != auth.RoleOwner {
+		if err := s.keepOwner(teamID); err != nil {
+			return nil, err
+		}
+	}
+	member.Set("role", role)
+	if err := s.app.Dao().SaveRecord(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember takes a user out of the team (also used to leave it)
+func (s *Service) RemoveMember(teamID string, userID string) error {
+	member, err := s.member(teamID, userID)
+	if err != nil {
+		return err
+	}
+	if member.GetString("role") == auth.RoleOwner {
+		if err := s.keepOwner(teamID); err != nil {
+			return err
+		}
+	}
+	return s.app.Dao().DeleteRecord(member)
+}
+
+func (s *Service) member(teamID string, userID string) (*models.Record, error) {
+	member, err := s.app.Dao().FindFirstRecordByFilter(
+		"team_members", "team = {:team} && user = {:user}", dbx.Params{"team": teamID, "user": userID},
+	)
+	if err != nil {
+		return nil, ErrNotMember
+	}
+	return member, nil
+}
+
+// keepOwner fails unless the team has another owner besides the one being changed
+func (s *Service) keepOwner(teamID string) error {
+	owners, err := s.app.Dao().FindRecordsByFilter(
+		"team_members", "team = {:team} && role = {:role}", "", 2, 0,
+		dbx.Params{"team": teamID, "role": auth.RoleOwner},
+	)
+	if err != nil {
+		return err
+	}
+	if len(owners) < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *Service) addMember(dao *daos.Dao, teamID string, userID string, role string) error {
+	members, err := dao.FindCollectionByNameOrId("team_members")
+	if err != nil {
+		return err
+	}
+	member := models.NewRecord(members)
+	member.Set("team", teamID)
+	member.Set("user", userID)
+	member.Set("role", role)
+	return dao.SaveRecord(member)
+}
+
+// Invite records an invitation for the email address. Send it with SendInvitation.
+func (s *Service) Invite(team *models.Record, inviter *models.Record, email string, role string) (*models.Record, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email address: %w", err)
+	}
+	email = strings.ToLower(address.Address)
+	if !auth.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	collection, err := s.app.Dao().FindCollectionByNameOrId("team_invitations")
+	if err != nil {
+		return nil, err
+	}
+	invitation := models.NewRecord(collection)
+	invitation.Set("team", team.Id)
+	invitation.Set("email", email)
+	invitation.Set("role", role)
+	invitation.Set("token", security.RandomString(40))
+	invitation.Set("status", InvitationPending)
+	invitation.Set("expires_at", time.Now().Add(InvitationTTL).UTC())
+	if inviter != nil {
+		invitation.Set("invited_by", inviter.Id)
+	}
+	if err := s.app.Dao().SaveRecord(invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// InvitationURL is the dashboard page that accepts an invitation
+func (s *Service) InvitationURL(invitation *models.Record) string {
+	return strings.TrimRight(s.app.Settings().Meta.AppUrl, "/") + "/team?invite=" + invitation.GetString("token")
+}
+
+// SendInvitation emails the invitation link through the PocketBase mailer (SMTP
+// settings of the admin UI). A failed send leaves the invitation valid, so the link
+// can still be shared by hand.
+func (s *Service) SendInvitation(team *models.Record, inviter *models.Record, invitation *models.Record) error {
+	meta := s.app.Settings().Meta
+	from := "An administrator"
+	if inviter != nil {
+		from = inviter.Email()
+	}
+	link := s.InvitationURL(invitation)
+	teamName := team.GetString("name")
+	role := invitation.GetString("role")
+
+	err := s.app.NewMailClient().Send(&mailer.Message{
+		From:    mail.Address{Name: meta.SenderName, Address: meta.SenderAddress},
+		To:      []mail.Address{{Address: invitation.GetString("email")}},
+		Subject: fmt.Sprintf("You have been invited to %s on %s", teamName, meta.AppName),
+		HTML: fmt.Sprintf(
+			`<p>%s invited you to join the team <strong>%s</strong> as <strong>%s</strong>.</p>`+
+				`<p><a href="%s">Accept the invitation</a></p>`+
+				`<p>The link expires in %d days. If you did not expect this email you can ignore it.</p>`,
+			html.EscapeString(from), html.EscapeString(teamName), role, html.EscapeString(link), int(InvitationTTL.Hours()/24),
+		),
+		Text: fmt.Sprintf(
+			"%s invited you to join the team %s as %s.\n\nAccept the invitation: %s\n\nThe link expires in %d days.",
+			from, teamName, role, link, int(InvitationTTL.Hours()/24),
+		),
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to email invitation to %s: %v", invitation.GetString("email"), err)
+		return err
+	}
+	log.Printf("✉️ Invitation to team '%s' sent to %s (%s)", teamName, invitation.GetString("email"), role)
+	return nil
+}
+
+// Invitations returns the pending invitations of a team
+func (s *Service) Invitations(teamID string) ([]*models.Record, error) {
+	return s.app.Dao().FindRecordsByFilter(
+		"team_invitations", "team = {:team} && status = {:status}", "-created", 0, 0,
+		dbx.Params{"team": teamID, "status": InvitationPending},
+	)
+}
+
+// Revoke cancels a pending invitation of the team
+func (s *Service) Revoke(teamID string, invitationID string) error {
+	invitation, err := s.app.Dao().FindRecordById("team_invitations", invitationID)
+	if err != nil || invitation.GetString("team") != teamID || invitation.GetString("status") != InvitationPending {
+		return ErrInvitationInvalid
+	}
+	invitation.Set("status", InvitationRevoked)
+	return s.app.Dao().SaveRecord(invitation)
+}
+
+// FindInvitation returns the pending, unexpired invitation with the token
+func (s *Service) FindInvitation(token string) (*models.Record, error) {
+	if token == "" {
+		return nil, ErrInvitationInvalid
+	}
+	invitation, err := s.app.Dao().FindFirstRecordByData("team_invitations", "token", token)
+	if err != nil || invitation.GetString("status") != InvitationPending {
+		return nil, ErrInvitationInvalid
+	}
+	if invitation.GetDateTime("expires_at").Time().Before(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+	return invitation, nil
+}
+
+// Accept adds the user to the invitation's team. The account must use the invited
+// email address. Existing members keep their current role.
+func (s *Service) Accept(token string, user *models.Record) (*models.Record, error) {
+	invitation, err := s.FindInvitation(token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(invitation.GetString("email"), user.Email()) {
+		return nil, ErrEmailMismatch
+	}
+
+	teamID := invitation.GetString("team")
+	_, memberErr := s.member(teamID, user.Id)
+	err = s.app.Dao().RunInTransaction(func(tx *daos.Dao) error {
+		if memberErr != nil {
+			if err := s.addMember(tx, teamID, user.Id, invitation.GetString("role")); err != nil {
+				return err
+			}
+		}
+		invitation.Set("status", InvitationAccepted)
+		invitation.Set("accepted_by", user.Id)
+		invitation.Set("accepted_at", types.NowDateTime())
+		return tx.SaveRecord(invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("👥 %s joined team %s as %s", user.Email(), teamID, invitation.GetString("role"))
+	return invitation, nil
+}
+
+// MoveProject shares the project (and its previews) with a team, or makes it personal
+// again when teamID is empty
+func (s *Service) MoveProject(project *models.Record, teamID string) error {
+	previews, err := s.app.Dao().FindRecordsByFilter("projects", "preview_of = {:parent}", "", 0, 0, dbx.Params{"parent": project.Id})
+	if err != nil {
+		return err
+	}
+	return s.app.Dao().RunInTransaction(func(tx *daos.Dao) error {
+		for _, p := range append(previews, project) {
+			p.Set("team", teamID)
+			if err := tx.SaveRecord(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package terminal
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"golang.org/x/net/websocket"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// Handler exposes the web terminal
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new terminal handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the exec endpoint
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/deploy/:id/exec", h.handleExec)
+}
+
+// handleExec upgrades to a WebSocket and opens a shell in a project container
+// URL: GET /api/senvanda/deploy/:id/exec?shell=/bin/bash&replica=1&token=<auth token>
+func (h *Handler) handleExec(c echo.Context) error {
+	// Recorded when the session ends, refused attempts included
+	audit.SetAction(c, "project.exec")
+
+	project, err := h.service.app.Dao().FindRecordById("projects", c.PathParam("id"))
+	if err != nil {
+		return apis.NewNotFoundError("Project not found", err)
+	}
+
+	// The auth middleware resolves ?token=; admins are not let in, only users with the owner role
+	user := auth.User(c)
+	if err := Authorize(user, auth.RoleFromContext(c)); err != nil {
+		return apis.NewForbiddenError(err.Error(), nil)
+	}
+
+	shell, err := ValidShell(c.QueryParam("shell"))
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), nil)
+	}
+
+	replica, _ := strconv.Atoi(c.QueryParam("replica"))
+	audit.Param(c, "shell", shell)
+	audit.Param(c, "replica", replica)
+	containerName, err := h.service.Target(c.Request().Context(), project, replica)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	audit.Param(c, "container", containerName)
+
+	server := websocket.Server{
+		// Auth is token based (no cookies), so cross-origin upgrades carry no ambient credentials
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			h.service.Session(ws, project, user, containerName, shell)
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
@@ -0,0 +1,260 @@
+package terminal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"golang.org/x/net/websocket"
+
+	"github.com/senvanda/backend/internal/auth"
+	"github.com/senvanda/backend/internal/infrastructure/docker"
+	"github.com/senvanda/backend/internal/orchestrator"
+)
+
+// DefaultShell is used when the client does not ask for one
+const DefaultShell = "/bin/sh"
+
+// Shells a session may start (anything else would be arbitrary command execution)
+var allowedShells = map[string]bool{
+	"/bin/sh":   true,
+	"/bin/ash":  true,
+	"/bin/bash": true,
+	"/bin/zsh":  true,
+}
+
+// Reasons a session ended, stored in `exec_sessions`
+const (
+	EndClosed = "closed"       // Client disconnected
+	EndExited = "exited"       // Shell exited
+	EndIdle   = "idle_timeout" // No input or output for the idle timeout
+	EndError  = "error"
+)
+
+var (
+	// ErrNotOwner is returned when the caller is not an owner of the project
+	ErrNotOwner = errors.New("only project owners can open a terminal")
+
+	// ErrShellNotAllowed is returned for shells outside allowedShells
+	ErrShellNotAllowed = errors.New("shell not allowed")
+)
+
+// Service opens audited TTY exec sessions into project containers
+type Service struct {
+	app          core.App
+	dockerClient *docker.Client
+	orchestrator *orchestrator.Service
+	idleTimeout  time.Duration
+}
+
+// NewService creates the terminal service. idleTimeout <= 0 means 15 minutes.
+func NewService(app core.App, dockerClient *docker.Client, orchestratorSvc *orchestrator.Service, idleTimeout time.Duration) *Service {
+	if idleTimeout <= 0 {
+		idleTimeout = 15 * time.Minute
+	}
+	return &Service{
+		app:          app,
+		dockerClient: dockerClient,
+		orchestrator: orchestratorSvc,
+		idleTimeout:  idleTimeout,
+	}
+}
+
+// Authorize checks that a user (not an admin) holds the owner role on the project:
+// its personal owner or an owner of its team
+func Authorize(user *models.Record, role string) error {
+	if user == nil || role != auth.RoleOwner {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// ValidShell returns the shell to start, DefaultShell when empty
+func ValidShell(shell string) (string, error) {
+	if shell == "" {
+		return DefaultShell, nil
+	}
+	if !allowedShells[shell] {
+		return "", ErrShellNotAllowed
+	}
+	return shell, nil
+}
+
+// Target returns the container a session attaches to: replica n (1-based, 0 = first running)
+func (s *Service) Target(ctx context.Context, project *models.Record, n int) (string, error) {
+	spec, err := orchestrator.SpecFromProject(project)
+	if err != nil {
+		return "", err
+	}
+	names, err := s.orchestrator.ReplicaNames(ctx, project, spec)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("project has no running containers")
+	}
+	if n < 1 {
+		return names[0], nil
+	}
+	if n > len(names) {
+		return "", fmt.Errorf("replica %d is not running (%d running)", n, len(names))
+	}
+	return names[n-1], nil
+}
+
+// clientMessage is sent by the browser as a JSON text frame:
+// {"type": "input", "data": "ls\r"} or {"type": "resize", "cols": 120, "rows": 30}
+type clientMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Cols uint   `json:"cols"`
+	Rows uint   `json:"rows"`
+}
+
+// Session bridges a WebSocket to a TTY exec in the container until either side
+// closes or nothing happens for the idle timeout. Terminal output is sent as binary frames.
+func (s *Service) Session(ws *websocket.Conn, project *models.Record, user *models.Record, containerName string, shell string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer ws.Close()
+
+	audit := s.recordStart(project, user, containerName, shell, ws.Request().RemoteAddr)
+	log.Printf("🖥️ Terminal opened by %s on %s (%s)", user.Id, containerName, shell)
+
+	execID, attach, err := s.dockerClient.ExecTTY(ctx, containerName, []string{shell}, []string{"TERM=xterm-256color"})
+	if err != nil {
+		_ = websocket.Message.Send(ws, fmt.Sprintf("\r\n❌ %v\r\n", err))
+		s.recordEnd(audit, EndError, -1, err.Error())
+		return
+	}
+	defer attach.Close()
+
+	var (
+		lastActivity atomic.Int64
+		endOnce      sync.Once
+		reason       = EndClosed
+	)
+	touch := func() { lastActivity.Store(time.Now().UnixNano()) }
+	end := func(r string) {
+		endOnce.Do(func() {
+			reason = r
+			attach.Close()
+			ws.Close()
+		})
+	}
+	touch()
+
+	// Container -> browser
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := attach.Reader.Read(buf)
+			if n > 0 {
+				touch()
+				if sendErr := websocket.Message.Send(ws, buf[:n]); sendErr != nil {
+					end(EndClosed)
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					end(EndExited)
+				} else {
+					end(EndClosed)
+				}
+				return
+			}
+		}
+	}()
+
+	// Idle watchdog
+	go func() {
+		ticker := time.NewTicker(time.Second * 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, lastActivity.Load())) > s.idleTimeout {
+					_ = websocket.Message.Send(ws, fmt.Sprintf("\r\n⌛ Session closed after %s of inactivity\r\n", s.idleTimeout))
+					end(EndIdle)
+					return
+				}
+			}
+		}
+	}()
+
+	// Browser -> container
+	for {
+		var raw string
+		if err := websocket.Message.Receive(ws, &raw); err != nil {
+			end(EndClosed)
+			break
+		}
+		touch()
+
+		var msg clientMessage
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "input":
+			if _, err := attach.Conn.Write([]byte(msg.Data)); err != nil {
+				end(EndExited)
+			}
+		case "resize":
+			if msg.Cols > 0 && msg.Rows > 0 {
+				_ = s.dockerClient.ResizeExec(ctx, execID, msg.Rows, msg.Cols)
+			}
+		}
+	}
+
+	exitCode, _ := s.dockerClient.ExecExitCode(context.Background(), execID)
+	log.Printf("🖥️ Terminal of %s on %s ended (%s)", user.Id, containerName, reason)
+	s.recordEnd(audit, reason, exitCode, "")
+}
+
+func (s *Service) recordStart(project *models.Record, user *models.Record, containerName string, shell string, remoteAddr string) *models.Record {
+	collection, err := s.app.Dao().FindCollectionByNameOrId("exec_sessions")
+	if err != nil {
+		return nil
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("project", project.Id)
+	record.Set("user", user.Id)
+	record.Set("container", containerName)
+	record.Set("shell", shell)
+	record.Set("remote_addr", remoteAddr)
+	record.Set("started_at", types.NowDateTime())
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to record terminal session: %v", err)
+		return nil
+	}
+	return record
+}
+
+func (s *Service) recordEnd(record *models.Record, reason string, exitCode int, errMsg string) {
+	if record == nil {
+		return
+	}
+	started := record.GetDateTime("started_at").Time()
+	record.Set("ended_at", types.NowDateTime())
+	record.Set("end_reason", reason)
+	record.Set("exit_code", exitCode)
+	record.Set("duration_ms", time.Since(started).Milliseconds())
+	record.Set("error", errMsg)
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to record terminal session end: %v", err)
+	}
+}
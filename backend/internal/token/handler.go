@@ -0,0 +1,124 @@
+package token
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/audit"
+	"github.com/senvanda/backend/internal/auth"
+)
+
+// Handler exposes personal access token management
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new token handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the token endpoints. The group must require auth.
+func (h *Handler) RegisterRoutes(g *echo.Group) {
+	g.GET("/tokens", h.handleList)
+	g.POST("/tokens", h.handleCreate)
+	g.DELETE("/tokens/:tokenId", h.handleRevoke)
+}
+
+type tokenView struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Hint       string   `json:"hint"` // First characters, to recognise the token
+	Scopes     []string `json:"scopes"`
+	Projects   []string `json:"projects"`
+	User       string   `json:"user,omitempty"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	LastUsedIP string   `json:"last_used_ip,omitempty"`
+	RevokedAt  string   `json:"revoked_at,omitempty"`
+	Created    string   `json:"created"`
+	Token      string   `json:"token,omitempty"` // Only in the create response
+}
+
+func toView(r *models.Record) tokenView {
+	view := tokenView{
+		ID:         r.Id,
+		Name:       r.GetString("name"),
+		Hint:       r.GetString("hint"),
+		Scopes:     []string{},
+		Projects:   []string{},
+		User:       r.GetString("user"),
+		ExpiresAt:  r.GetString("expires_at"),
+		LastUsedAt: r.GetString("last_used_at"),
+		LastUsedIP: r.GetString("last_used_ip"),
+		RevokedAt:  r.GetString("revoked_at"),
+		Created:    r.Created.String(),
+	}
+	_ = r.UnmarshalJSONField("scopes", &view.Scopes)
+	_ = r.UnmarshalJSONField("projects", &view.Projects)
+	return view
+}
+
+func owner(c echo.Context) Owner {
+	admin, _ := c.Get(apis.ContextAdminKey).(*models.Admin)
+	return Owner{User: auth.User(c), Admin: admin}
+}
+
+// handleList returns the caller's tokens (every token for admins)
+// URL: GET /api/senvanda/tokens
+func (h *Handler) handleList(c echo.Context) error {
+	records, err := h.service.List(owner(c))
+	if err != nil {
+		return apis.NewBadRequestError("Failed to list API tokens", err)
+	}
+	items := make([]tokenView, 0, len(records))
+	for _, r := range records {
+		items = append(items, toView(r))
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// handleCreate issues a token. The plain token is only returned here.
+// URL: POST /api/senvanda/tokens {"name": "ci", "scopes": ["projects:deploy"], "projects": ["<id>"], "expires_in_days": 90}
+func (h *Handler) handleCreate(c echo.Context) error {
+	var req CreateReq
+	if err := c.Bind(&req); err != nil {
+		return apis.NewBadRequestError("Invalid request", err)
+	}
+	for _, projectID := range req.Projects {
+		if !auth.CanAccessProject(h.service.app, c, projectID) {
+			return apis.NewForbiddenError("You cannot restrict a token to project "+projectID, nil)
+		}
+	}
+
+	audit.SetAction(c, "token.create")
+	audit.Param(c, "name", req.Name)
+	audit.Param(c, "scopes", req.Scopes)
+	audit.Param(c, "projects", req.Projects)
+
+	record, plain, err := h.service.Create(owner(c), req)
+	if err != nil {
+		return apis.NewBadRequestError(err.Error(), err)
+	}
+	view := toView(record)
+	view.Token = plain
+	return c.JSON(http.StatusCreated, view)
+}
+
+// handleRevoke disables a token; it stays listed as revoked
+// URL: DELETE /api/senvanda/tokens/:tokenId
+func (h *Handler) handleRevoke(c echo.Context) error {
+	audit.SetAction(c, "token.revoke")
+	record, err := h.service.Revoke(owner(c), c.PathParam("tokenId"))
+	if errors.Is(err, ErrNotFound) {
+		return apis.NewNotFoundError(err.Error(), err)
+	}
+	if err != nil {
+		return apis.NewBadRequestError("Failed to revoke API token", err)
+	}
+	return c.JSON(http.StatusOK, toView(record))
+}
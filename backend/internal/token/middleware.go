@@ -0,0 +1,109 @@
+package token
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/apis"
+
+	"github.com/senvanda/backend/internal/audit"
+)
+
+const contextKey = "senvanda.apiToken"
+
+// FromContext returns the API token the request authenticated with, nil for sessions
+func FromContext(c echo.Context) *Token {
+	t, _ := c.Get(contextKey).(*Token)
+	return t
+}
+
+// listRoutes answer with every project the caller can see. Restricted tokens may call
+// them; the handler keeps only the token's projects (see AllowsProject).
+var listRoutes = map[string]bool{
+	"GET /deploy/projects": true,
+}
+
+// AllowsProject reports whether the API token of the request, if any, may see projectID
+func AllowsProject(c echo.Context, projectID string) bool {
+	t := FromContext(c)
+	return t == nil || t.AllowsProject(projectID)
+}
+
+// requiredScope is the scope a token needs for a route of the /api/senvanda group
+func requiredScope(method string, route string) string {
+	switch {
+	// Shells and access management are never delegated to narrower scopes
+	case route == "/deploy/:id/exec", route == "/deploy/:id/team",
+		strings.HasPrefix(route, "/tokens"), strings.HasPrefix(route, "/teams"),
+		strings.HasPrefix(route, "/invitations"), strings.HasPrefix(route, "/audit"):
+		return ScopeAdmin
+	case strings.HasPrefix(route, "/deploy/:id/logs"):
+		return ScopeLogsRead
+	case method == http.MethodGet || method == http.MethodHead:
+		return ScopeProjectsRead
+	case strings.HasPrefix(route, "/deploy"), strings.HasPrefix(route, "/jobs"):
+		return ScopeProjectsDeploy
+	default:
+		return ScopeAdmin
+	}
+}
+
+// Middleware signs in requests carrying a personal access token
+// (`Authorization: Bearer svd_...`) as the token's owner and enforces its scopes and
+// project restriction. Other requests pass through untouched. Role checks on
+// projects still apply on top: a token never grants more than its owner has.
+func (s *Service) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			plain := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+			if !strings.HasPrefix(plain, Prefix) {
+				return next(c)
+			}
+
+			t, err := s.Authenticate(plain, c.RealIP())
+			if err != nil {
+				return apis.NewUnauthorizedError(err.Error(), nil)
+			}
+			if t.Owner.User != nil {
+				c.Set(apis.ContextAuthRecordKey, t.Owner.User)
+			} else {
+				c.Set(apis.ContextAdminKey, t.Owner.Admin)
+			}
+			c.Set(contextKey, t)
+			audit.Param(c, "api_token", t.Record.Id)
+
+			if err := s.authorize(c, t); err != nil {
+				return apis.NewForbiddenError(err.Error(), nil)
+			}
+			return next(c)
+		}
+	}
+}
+
+// authorize checks the token's scope and project restriction for the current route
+func (s *Service) authorize(c echo.Context, t *Token) error {
+	route := strings.TrimPrefix(c.Path(), "/api/senvanda")
+	if scope := requiredScope(c.Request().Method, route); !t.HasScope(scope) {
+		return errors.New("this API token lacks the " + scope + " scope")
+	}
+	if len(t.Projects) == 0 {
+		return nil
+	}
+
+	// Restricted tokens only reach routes of their projects
+	projectID := c.PathParam("id")
+	if jobID := c.PathParam("jobId"); projectID == "" && jobID != "" {
+		if job, err := s.app.Dao().FindRecordById("deploy_jobs", jobID); err == nil {
+			projectID = job.GetString("project")
+		}
+	}
+	if projectID == "" && listRoutes[c.Request().Method+" "+route] {
+		return nil
+	}
+	if projectID == "" || !t.AllowsProject(projectID) {
+		return errors.New("this API token is restricted to other projects")
+	}
+	return nil
+}
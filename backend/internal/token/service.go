@@ -0,0 +1,205 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Prefix marks personal access tokens, so they are never mistaken for PocketBase sessions
+const Prefix = "svd_"
+
+// Scopes a token can carry
+const (
+	ScopeProjectsRead   = "projects:read"   // Project state, history, metrics
+	ScopeProjectsDeploy = "projects:deploy" // Deploy, redeploy, scale, rollback, settings
+	ScopeLogsRead       = "logs:read"       // Container logs
+	ScopeAdmin          = "admin"           // Everything the owner can do, including the terminal and tokens
+)
+
+var allScopes = []string{ScopeProjectsRead, ScopeProjectsDeploy, ScopeLogsRead, ScopeAdmin}
+
+// lastUsedEvery throttles the last-used bookkeeping to one write per token per interval
+const lastUsedEvery = time.Minute
+
+var (
+	// ErrInvalid is returned for unknown, revoked and expired tokens
+	ErrInvalid = errors.New("invalid or expired API token")
+
+	// ErrNotFound is returned when the token does not exist or belongs to someone else
+	ErrNotFound = errors.New("API token not found")
+)
+
+// CreateReq describes a new token
+type CreateReq struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	Projects      []string `json:"projects"`        // Empty = every project the owner can access
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = never expires
+}
+
+// Owner is who a token acts as: a user or a PocketBase admin
+type Owner struct {
+	User  *models.Record
+	Admin *models.Admin
+}
+
+// Token is an authenticated token with its owner
+type Token struct {
+	Record   *models.Record
+	Owner    Owner
+	Scopes   []string
+	Projects []string
+}
+
+// HasScope reports whether the token carries scope (admin carries every scope)
+func (t *Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+// AllowsProject reports whether the token may act on the project
+func (t *Token) AllowsProject(projectID string) bool {
+	return len(t.Projects) == 0 || slices.Contains(t.Projects, projectID)
+}
+
+// Service issues and checks personal access tokens. Only the SHA-256 of a token is
+// stored; the token itself is shown once, when it is created.
+type Service struct {
+	app core.App
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time // token id -> last recorded use
+}
+
+// NewService creates a new token service
+func NewService(app core.App) *Service {
+	return &Service{app: app, lastSeen: make(map[string]time.Time)}
+}
+
+// Create issues a token for owner. Returns the record and the plain token.
+func (s *Service) Create(owner Owner, req CreateReq) (*models.Record, string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, "", errors.New("token name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(allScopes, scope) {
+			return nil, "", fmt.Errorf("unknown scope %q (use %s)", scope, strings.Join(allScopes, ", "))
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, "", errors.New("expires_in_days cannot be negative")
+	}
+
+	collection, err := s.app.Dao().FindCollectionByNameOrId("api_tokens")
+	if err != nil {
+		return nil, "", err
+	}
+
+	plain := Prefix + security.RandomString(40)
+	record := models.NewRecord(collection)
+	record.Set("name", req.Name)
+	record.Set("hash", security.SHA256(plain))
+	record.Set("hint", plain[:len(Prefix)+6])
+	record.Set("scopes", slices.Compact(slices.Sorted(slices.Values(req.Scopes))))
+	record.Set("projects", req.Projects)
+	if owner.User != nil {
+		record.Set("user", owner.User.Id)
+	} else if owner.Admin != nil {
+		record.Set("admin", owner.Admin.Id)
+	}
+	if req.ExpiresInDays > 0 {
+		record.Set("expires_at", time.Now().Add(time.Duration(req.ExpiresInDays)*24*time.Hour).UTC())
+	}
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		return nil, "", err
+	}
+
+	log.Printf("🔑 API token '%s' created (%s)", req.Name, strings.Join(req.Scopes, ", "))
+	return record, plain, nil
+}
+
+// List returns the tokens of owner, newest first. An admin sees every token.
+func (s *Service) List(owner Owner) ([]*models.Record, error) {
+	if owner.Admin != nil {
+		return s.app.Dao().FindRecordsByFilter("api_tokens", "id != ''", "-created", 0, 0)
+	}
+	return s.app.Dao().FindRecordsByFilter("api_tokens", "user = {:user}", "-created", 0, 0, dbx.Params{"user": owner.User.Id})
+}
+
+// Revoke disables a token of owner (admins can revoke any token)
+func (s *Service) Revoke(owner Owner, tokenID string) (*models.Record, error) {
+	record, err := s.app.Dao().FindRecordById("api_tokens", tokenID)
+	if err != nil || (owner.Admin == nil && record.GetString("user") != owner.User.Id) {
+		return nil, ErrNotFound
+	}
+	if record.GetDateTime("revoked_at").IsZero() {
+		record.Set("revoked_at", types.NowDateTime())
+		if err := s.app.Dao().SaveRecord(record); err != nil {
+			return nil, err
+		}
+		log.Printf("🔒 API token '%s' revoked", record.GetString("name"))
+	}
+	return record, nil
+}
+
+// Authenticate resolves a plain token to its owner, recording when and from where it was used
+func (s *Service) Authenticate(plain string, ip string) (*Token, error) {
+	if !strings.HasPrefix(plain, Prefix) {
+		return nil, ErrInvalid
+	}
+	record, err := s.app.Dao().FindFirstRecordByData("api_tokens", "hash", security.SHA256(plain))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if !record.GetDateTime("revoked_at").IsZero() {
+		return nil, ErrInvalid
+	}
+	if expires := record.GetDateTime("expires_at"); !expires.IsZero() && expires.Time().Before(time.Now()) {
+		return nil, ErrInvalid
+	}
+
+	token := &Token{Record: record}
+	if userID := record.GetString("user"); userID != "" {
+		if token.Owner.User, err = s.app.Dao().FindRecordById("users", userID); err != nil {
+			return nil, ErrInvalid
+		}
+	} else if token.Owner.Admin, err = s.app.Dao().FindAdminById(record.GetString("admin")); err != nil {
+		return nil, ErrInvalid
+	}
+	_ = record.UnmarshalJSONField("scopes", &token.Scopes)
+	_ = record.UnmarshalJSONField("projects", &token.Projects)
+
+	s.touch(record, ip)
+	return token, nil
+}
+
+// touch records the last use, at most once per lastUsedEvery
+func (s *Service) touch(record *models.Record, ip string) {
+	s.mu.Lock()
+	if time.Since(s.lastSeen[record.Id]) < lastUsedEvery {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSeen[record.Id] = time.Now()
+	s.mu.Unlock()
+
+	record.Set("last_used_at", types.NowDateTime())
+	record.Set("last_used_ip", ip)
+	if err := s.app.Dao().SaveRecord(record); err != nil {
+		log.Printf("⚠️ Failed to record API token use: %v", err)
+	}
+}
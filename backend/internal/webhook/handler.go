@@ -3,20 +3,25 @@ package webhook
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v5"
+	"github.com/senvanda/backend/internal/audit"
 	"github.com/senvanda/backend/internal/orchestrator"
+	"github.com/senvanda/backend/internal/preview"
 )
 
 type Handler struct {
 	service      *Service
 	orchestrator *orchestrator.Service
+	previews     *preview.Service
 }
 
-func NewHandler(service *Service, orchestrator *orchestrator.Service) *Handler {
+func NewHandler(service *Service, orchestrator *orchestrator.Service, previews *preview.Service) *Handler {
 	return &Handler{
 		service:      service,
 		orchestrator: orchestrator,
+		previews:     previews,
 	}
 }
 
@@ -28,6 +33,20 @@ func (h *Handler) HandleGiteaPush(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "missing token"})
 	}
 
+	audit.SetAction(c, "webhook.push")
+
+	// Branch deleted: tear down its preview environment
+	if c.Request().Header.Get("X-Gitea-Event") == "delete" {
+		var payload GiteaDeletePayload
+		if err := c.Bind(&payload); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload format"})
+		}
+		if payload.RefType != "branch" {
+			return c.JSON(http.StatusOK, map[string]string{"status": "ignored", "message": "not a branch"})
+		}
+		return h.handleBranchDeleted(c, token, payload.Ref)
+	}
+
 	// 1. Parse Payload
 	var payload GiteaPushPayload
 	if err := c.Bind(&payload); err != nil {
@@ -35,13 +54,27 @@ func (h *Handler) HandleGiteaPush(c echo.Context) error {
 	}
 
 	log.Printf("📥 Webhook Received for repo: %s (Ref: %s)", payload.Repository.FullName, payload.Ref)
+	audit.Param(c, "repository", payload.Repository.FullName)
+	audit.Param(c, "ref", payload.Ref)
+	audit.Param(c, "commit", payload.After)
+
+	if payload.After == zeroCommit {
+		return h.handleBranchDeleted(c, token, strings.TrimPrefix(payload.Ref, "refs/heads/"))
+	}
 
 	// 2. Validate Token & Logic
-	project, err := h.service.ValidateTrigger(token, payload)
+	trigger, err := h.service.ValidateTrigger(token, payload)
 	if err != nil {
 		log.Printf("⛔ Webhook Rejected: %v", err)
 		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
 	}
+	project := trigger.Project
+	audit.SetActor(c, audit.ActorWebhook, project.Id, payload.Repository.FullName)
+	audit.SetProject(c, project.Id)
+
+	if trigger.Preview {
+		return h.handlePreviewPush(c, trigger)
+	}
 
 	log.Printf("✅ Webhook Accepted for Project: %s (ID: %s)", project.GetString("name"), project.Id)
 
@@ -60,6 +93,53 @@ func (h *Handler) HandleGiteaPush(c echo.Context) error {
 	})
 }
 
+// handlePreviewPush deploys a push to a non-production branch as its own preview environment
+func (h *Handler) handlePreviewPush(c echo.Context, trigger *Trigger) error {
+	preview, job, err := h.previews.Deploy(trigger.Project, trigger.Branch)
+	if err != nil {
+		log.Printf("❌ Failed to deploy preview of %s: %v", trigger.Branch, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to deploy preview"})
+	}
+
+	log.Printf("🌿 Preview %s queued for branch %s", preview.GetString("name"), trigger.Branch)
+	audit.Queued(c, job.Id)
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"status":     "queued",
+		"project_id": preview.Id,
+		"job_id":     job.Id,
+		"url":        "http://" + orchestrator.DefaultDomain(preview.GetString("name")),
+		"message":    "Preview deployment queued.",
+	})
+}
+
+// handleBranchDeleted tears down the preview of a deleted branch, if there is one
+func (h *Handler) handleBranchDeleted(c echo.Context, token string, branch string) error {
+	audit.SetAction(c, "webhook.branch_delete")
+	audit.Param(c, "branch", branch)
+	project, err := h.service.FindProject(token)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+	audit.SetActor(c, audit.ActorWebhook, project.Id, "webhook token")
+	audit.SetProject(c, project.Id)
+
+	job, err := h.previews.Destroy(project, branch)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to queue preview teardown"})
+	}
+	if job == nil {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ignored", "message": "no preview for branch " + branch})
+	}
+
+	log.Printf("🧹 Branch %s deleted, preview teardown queued", branch)
+	audit.Queued(c, job.Id)
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"status":  "queued",
+		"job_id":  job.Id,
+		"message": "Preview teardown queued.",
+	})
+}
+
 // RegisterRoutes registers the webhook endpoint
 func (h *Handler) RegisterRoutes(g *echo.Group) {
 	g.POST("/webhook/:token", h.HandleGiteaPush)
@@ -6,6 +6,8 @@ import (
 
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/models"
+
+	"github.com/senvanda/backend/internal/orchestrator"
 )
 
 // Service handles webhook business logic
@@ -18,33 +20,52 @@ func NewService(app core.App) *Service {
 	return &Service{app: app}
 }
 
-// ValidateTrigger checks token validity and branch rules
-func (s *Service) ValidateTrigger(token string, payload GiteaPushPayload) (*models.Record, error) {
-	// 1. Find Project by Token
-	project, err := s.app.Dao().FindRecordById("projects", token) // Assuming ID is token or we use FindFirstRecordByData
+// Trigger is the outcome of a validated push
+type Trigger struct {
+	Project *models.Record
+	Branch  string
+	Preview bool // Push to another branch that matches the project's preview rules
+}
+
+// ValidateTrigger checks token validity and branch rules.
+// Pushes to the configured branch deploy the project itself; other branches are only
+// accepted when the project opted into preview environments.
+func (s *Service) ValidateTrigger(token string, payload GiteaPushPayload) (*Trigger, error) {
+	project, err := s.FindProject(token)
 	if err != nil {
-		// Fallback: Check if 'webhookToken' is a separate field (Best Practice)
-		p, errData := s.app.Dao().FindFirstRecordByData("projects", "webhookToken", token)
-		if errData != nil {
-			return nil, errors.New("invalid webhook token: project not found")
-		}
-		project = p
+		return nil, err
 	}
 
 	// 2. Validate Branch (Defense Mechanism)
 	allowedBranch := "main" // fallback
-	if settingsData := project.Get("settings"); settingsData != nil {
-		if settings, ok := settingsData.(map[string]interface{}); ok {
-			if b, ok := settings["branch"].(string); ok && b != "" {
-				allowedBranch = b
-			}
-		}
+	spec, specErr := orchestrator.SpecFromProject(project)
+	if specErr == nil && spec.Branch != "" {
+		allowedBranch = spec.Branch
 	}
 
 	targetBranch := strings.TrimPrefix(payload.Ref, "refs/heads/")
-	if targetBranch != allowedBranch {
-		return nil, errors.New("ignoring push: not to the configured branch (" + allowedBranch + ")")
+	if targetBranch == allowedBranch {
+		return &Trigger{Project: project, Branch: targetBranch}, nil
+	}
+
+	if specErr == nil && spec.Previews != nil && spec.Previews.Matches(targetBranch) && project.GetString("preview_of") == "" {
+		return &Trigger{Project: project, Branch: targetBranch, Preview: true}, nil
 	}
 
+	return nil, errors.New("ignoring push: not to the configured branch (" + allowedBranch + ")")
+}
+
+// FindProject resolves a webhook token to its project
+func (s *Service) FindProject(token string) (*models.Record, error) {
+	// 1. Find Project by Token
+	project, err := s.app.Dao().FindRecordById("projects", token) // Assuming ID is token or we use FindFirstRecordByData
+	if err != nil {
+		// Fallback: Check if 'webhookToken' is a separate field (Best Practice)
+		p, errData := s.app.Dao().FindFirstRecordByData("projects", "webhookToken", token)
+		if errData != nil {
+			return nil, errors.New("invalid webhook token: project not found")
+		}
+		project = p
+	}
 	return project, nil
 }
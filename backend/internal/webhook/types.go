@@ -11,6 +11,17 @@ type GiteaPushPayload struct {
 	Sender     Sender     `json:"sender"`
 }
 
+// GiteaDeletePayload is sent with `X-Gitea-Event: delete` when a branch or tag is removed
+type GiteaDeletePayload struct {
+	Ref        string     `json:"ref"`      // Branch name, without refs/heads/
+	RefType    string     `json:"ref_type"` // "branch" or "tag"
+	Repository Repository `json:"repository"`
+	Sender     Sender     `json:"sender"`
+}
+
+// zeroCommit is the "after" of a push that deletes the branch
+const zeroCommit = "0000000000000000000000000000000000000000"
+
 type Repository struct {
 	ID       int64  `json:"id"`
 	Name     string `json:"name"`